@@ -2,92 +2,299 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 
 	"pre-test-gallery-service/docs"
 	"pre-test-gallery-service/internal/config"
 	"pre-test-gallery-service/internal/handlers"
+	"pre-test-gallery-service/internal/migrations"
 	"pre-test-gallery-service/internal/repository"
+	"pre-test-gallery-service/internal/repository/sqlstore"
 	"pre-test-gallery-service/internal/routes"
 	"pre-test-gallery-service/internal/service"
+	"pre-test-gallery-service/pkg/auth"
 	"pre-test-gallery-service/pkg/database"
+	"pre-test-gallery-service/pkg/health"
+	"pre-test-gallery-service/pkg/logger"
+	"pre-test-gallery-service/pkg/middleware"
+	"pre-test-gallery-service/pkg/migrate"
+	"pre-test-gallery-service/pkg/tracing"
 	"pre-test-gallery-service/pkg/utils"
 )
 
+// setupMongoDB waits up to MONGO_CONNECT_RETRY_TIMEOUT for MongoDB, so the
+// service can start alongside it; a signal stops the wait
 func setupMongoDB(cfg *config.Config) (*mongo.Client, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	return database.ConnectMongoDB(ctx, cfg.MongoOptions())
+}
+
+// storage is the metadata backend selected by STORAGE_BACKEND; exactly one
+// of mongoClient and sqlDB is set
+type storage struct {
+	tags        repository.TagsRepository
+	mongoClient *mongo.Client
+	sqlDB       *sqlstore.DB
+}
+
+// setupStorage connects the configured backend, applies pending migrations
+// when MIGRATE_ON_STARTUP is set and registers its readiness check
+func setupStorage(cfg *config.Config, healthChecks *health.Registry) (*storage, error) {
+	if cfg.StorageBackend != "mongodb" {
+		return setupSQL(cfg, healthChecks)
+	}
+
+	mongoClient, err := setupMongoDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.MigrateOnStartup {
+		runner, err := newMigrationRunner(cfg, mongoClient)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := runner.Up(context.Background()); err != nil {
+			return nil, fmt.Errorf("migrate: %w", err)
+		}
+	}
+
+	healthChecks.Register("mongodb", func(ctx context.Context) error {
+		return mongoClient.Ping(ctx, readpref.Primary())
+	})
+
+	db := mongoClient.Database(cfg.MongoDBDatabase)
+	return &storage{
+		tags:        repository.NewTagsRepository(db),
+		mongoClient: mongoClient,
+	}, nil
+}
+
+func setupSQL(cfg *config.Config, healthChecks *health.Registry) (*storage, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	client, err := database.ConnectMongoDB(cfg.MongoDBURI)
+	dialect := sqlstore.Dialect(cfg.StorageBackend)
+	db, err := sqlstore.Open(ctx, dialect, cfg.DatabaseURL)
 	if err != nil {
 		return nil, err
 	}
+	slog.Info("Connected to SQL database", "backend", cfg.StorageBackend)
 
-	if err := client.Ping(ctx, nil); err != nil {
-		return nil, err
+	if cfg.MigrateOnStartup {
+		if _, err := db.Migrate(context.Background()); err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("migrate: %w", err)
+		}
 	}
 
-	log.Println("Connected to MongoDB successfully")
-	return client, nil
+	healthChecks.Register(cfg.StorageBackend, db.PingContext)
+
+	return &storage{
+		tags:  sqlstore.NewTagsRepository(db),
+		sqlDB: db,
+	}, nil
+}
+
+func newMigrationRunner(cfg *config.Config, client *mongo.Client) (*migrate.Runner, error) {
+	db := client.Database(cfg.MongoDBDatabase)
+	return migrate.New(db, migrations.All(cfg.DefaultTenant), cfg.MigrationLockTimeout)
+}
+
+// setupRateLimiters also returns the Redis client when limits are shared,
+// so it can be closed on shutdown
+func setupRateLimiters(cfg *config.Config, healthChecks *health.Registry) (*middleware.RateLimiters, *redis.Client, error) {
+	policies, err := middleware.ParseRateLimitPolicies(cfg.RateLimitDefault, cfg.RateLimitPolicies)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cfg.RateLimitStore == "memory" {
+		return middleware.NewRateLimiters(policies, middleware.MemoryStoreFactory), nil, nil
+	}
+
+	opts, err := redis.ParseURL(cfg.RedisURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid REDIS_URL: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, nil, err
+	}
+
+	healthChecks.Register("redis", func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	})
+
+	slog.Info("Connected to Redis for rate limiting")
+	return middleware.NewRateLimiters(policies, middleware.RedisStoreFactory(client)), client, nil
+}
+
+func setupAuth(cfg *config.Config) (*service.AuthService, *auth.TokenIssuer, error) {
+	roleMapping, err := auth.ParseRoleMapping(cfg.OIDCRoleMapping)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	provider, err := auth.NewOIDCProvider(ctx, auth.OIDCConfig{
+		IssuerURL:    cfg.OIDCIssuerURL,
+		ClientID:     cfg.OIDCClientID,
+		ClientSecret: cfg.OIDCClientSecret,
+		RedirectURL:  cfg.OIDCRedirectURL,
+		Scopes:       cfg.OIDCScopes,
+		RoleMapper: auth.RoleMapper{
+			Claim:       cfg.OIDCRoleClaim,
+			Mapping:     roleMapping,
+			DefaultRole: cfg.OIDCDefaultRole,
+		},
+		TenantClaim: cfg.OIDCTenantClaim,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tokenIssuer := auth.NewTokenIssuer(cfg.JWTSecret, cfg.JWTExpiresIn)
+	slog.Info("OIDC login enabled", "issuer", cfg.OIDCIssuerURL)
+	return service.NewAuthService(provider, tokenIssuer), tokenIssuer, nil
+}
+
+func setupTracing(cfg *config.Config) (func(context.Context) error, error) {
+	return tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    cfg.TracesExporter,
+		Endpoint:    cfg.TracesEndpoint,
+		Insecure:    cfg.TracesInsecure,
+		ServiceName: cfg.TracesServiceName,
+		SampleRatio: cfg.TracesSampleRatio,
+	})
+}
+
+func setupCORS(cfg *config.Config) (fiber.Handler, error) {
+	// Browsers must be allowed to send the tenant header
+	allowHeaders := cfg.CORSAllowHeaders
+	if !slices.ContainsFunc(allowHeaders, func(h string) bool { return strings.EqualFold(h, cfg.TenantHeader) }) {
+		allowHeaders = append(slices.Clone(allowHeaders), cfg.TenantHeader)
+	}
+
+	return middleware.CORS(middleware.CORSConfig{
+		AllowOrigins:     cfg.CORSAllowOrigins,
+		AllowMethods:     cfg.CORSAllowMethods,
+		AllowHeaders:     allowHeaders,
+		ExposeHeaders:    cfg.CORSExposeHeaders,
+		AllowCredentials: cfg.CORSAllowCredentials,
+		MaxAge:           cfg.CORSMaxAge,
+	})
 }
 
 func setupServer(cfg *config.Config) (*routes.Application, error) {
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
-		AppName: "Go Fiber API v1.0",
-		ErrorHandler: func(c *fiber.Ctx, err error) error {
-			code := fiber.StatusInternalServerError
-			if e, ok := err.(*fiber.Error); ok {
-				code = e.Code
-			}
-			return c.Status(code).JSON(fiber.Map{
-				"error": err.Error(),
-			})
-		},
+		AppName:      "Go Fiber API v1.0",
+		ErrorHandler: utils.ErrorHandler,
+		// Route parameters such as Thai tag names arrive percent-encoded
+		UnescapePath: true,
+		// Imports read their body as a stream; other routes buffer it up to
+		// BodyLimit with middleware.BufferBody
+		StreamRequestBody: true,
 	})
 
-	docs.UpdateSwaggerHost(cfg.ServerHost, cfg.ServerPort)
-	utils.SetupValidator()
+	docs.UpdateSwaggerHost(cfg.ServerHost, strconv.Itoa(cfg.ServerPort))
+	if err := utils.SetupValidator(); err != nil {
+		return nil, err
+	}
+
+	// Tracing, request IDs and structured access logs
+	app.Use(middleware.Tracing())
+	app.Use(middleware.RequestID(slog.Default()))
+	app.Use(middleware.Language(cfg.DefaultLanguage))
+
+	// Resolve the real client IP behind trusted proxies
+	clientIPResolver, err := middleware.NewClientIPResolver(cfg.TrustedProxies, cfg.ClientIPHeaders)
+	if err != nil {
+		return nil, err
+	}
+	app.Use(middleware.ClientIP(clientIPResolver))
+	app.Use(middleware.RequestLogger())
+	app.Use(middleware.Metrics())
 
 	// Setup CORS
-	app.Use(cors.New(cors.Config{
-		AllowOrigins:     "*",
-		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS,PATCH",
-		AllowHeaders:     "Origin,Authorization,Content-Type",
-		ExposeHeaders:    "Content-Length",
-		AllowCredentials: false,
-		MaxAge:           12 * 60 * 60, // 12 hours
-	}))
-
-	// Setup MongoDB
-	mongoClient, err := setupMongoDB(cfg)
+	corsHandler, err := setupCORS(cfg)
 	if err != nil {
 		return nil, err
 	}
+	app.Use(corsHandler)
 
-	// Initialize repositories
-	db := mongoClient.Database(cfg.MongoDBDatabase)
-	tagsRepository := repository.NewTagsRepository(db)
+	// Readiness checks
+	healthChecks := health.NewRegistry(2 * time.Second)
+
+	// Setup storage and repositories
+	store, err := setupStorage(cfg, healthChecks)
+	if err != nil {
+		return nil, err
+	}
 
 	// // Initialize services
-	tagsService := service.NewTagsService(tagsRepository)
+	tagsService := service.NewTagsService(store.tags)
 
 	// // Initialize handlers
 	tagsHandler := handlers.NewTagsHandler(tagsService)
+	healthHandler := handlers.NewHealthHandler(healthChecks)
+
+	// Setup rate limits
+	rateLimiters, redisClient, err := setupRateLimiters(cfg, healthChecks)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create application instance
 	application := &routes.Application{
-		App:         app,
-		TagsHandler: tagsHandler,
-		Config:      cfg,
+		App:            app,
+		TagsHandler:    tagsHandler,
+		HealthHandler:  healthHandler,
+		HealthChecks:   healthChecks,
+		RateLimiters:   rateLimiters,
+		MongoClient:    store.mongoClient,
+		RedisClient:    redisClient,
+		RequestTimeout: cfg.RequestTimeout,
+		Config:         cfg,
+	}
+
+	if store.sqlDB != nil {
+		application.SQLDB = store.sqlDB.DB
+	}
+
+	// Setup OIDC login
+	if cfg.OIDCEnabled() {
+		authService, tokenIssuer, err := setupAuth(cfg)
+		if err != nil {
+			return nil, err
+		}
+		application.AuthHandler = handlers.NewAuthHandler(authService, cfg.IsProduction())
+		application.TokenIssuer = tokenIssuer
 	}
 
 	// Setup routes
@@ -96,6 +303,50 @@ func setupServer(cfg *config.Config) (*routes.Application, error) {
 	return application, nil
 }
 
+// shutdown fails readiness and keeps serving for the drain delay, stops
+// accepting connections and drains in-flight requests, then flushes
+// telemetry and closes the database clients, all before ctx expires
+func shutdown(ctx context.Context, application *routes.Application, shutdownTracing func(context.Context) error) error {
+	var errs []error
+
+	application.HealthChecks.Drain()
+	if delay := application.Config.ShutdownDrainDelay; delay > 0 {
+		slog.Info("Failing readiness before closing connections", "delay", delay.String())
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
+	}
+
+	deadline, _ := ctx.Deadline()
+	if err := application.App.ShutdownWithTimeout(time.Until(deadline)); err != nil {
+		errs = append(errs, fmt.Errorf("http server: %w", err))
+	}
+
+	if err := shutdownTracing(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("tracing: %w", err))
+	}
+
+	if application.RedisClient != nil {
+		if err := application.RedisClient.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("redis: %w", err))
+		}
+	}
+
+	if application.MongoClient != nil {
+		if err := application.MongoClient.Disconnect(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("mongodb: %w", err))
+		}
+	}
+	if application.SQLDB != nil {
+		if err := application.SQLDB.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("sql: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
 // @title Service Gallery
 // @version 1.0
 // @description A RESTful API server
@@ -109,11 +360,36 @@ func setupServer(cfg *config.Config) (*routes.Application, error) {
 // @schemes http https
 // @in header
 func main() {
-	cfg := config.LoadConfig()
+	configFile := flag.String("config", "", "path to a YAML or TOML config file (defaults to $CONFIG_FILE)")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	flag.Parse()
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	slog.SetDefault(logger.New(os.Stdout, cfg.LogLevel))
+
+	shutdownTracing, err := setupTracing(cfg)
+	if err != nil {
+		slog.Error("Failed to setup tracing", "error", err)
+		os.Exit(1)
+	}
 
 	application, err := setupServer(cfg)
 	if err != nil {
-		log.Fatal("Failed to setup server:", err)
+		slog.Error("Failed to setup server", "error", err)
+		os.Exit(1)
 	}
 
 	// Graceful shutdown channel
@@ -121,19 +397,25 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
 	go func() {
-		addr := cfg.ServerHost + ":" + cfg.ServerPort
-		log.Printf("Server starting on %s", addr)
+		addr := cfg.ServerHost + ":" + strconv.Itoa(cfg.ServerPort)
+		slog.Info("Server starting", "addr", addr)
 		if err := application.App.Listen(addr); err != nil {
-			log.Fatal("Failed to start server:", err)
+			slog.Error("Failed to start server", "error", err)
+			os.Exit(1)
 		}
 	}()
 
 	<-quit
-	log.Println("Shutting down server...")
+	slog.Info("Shutting down server...", "timeout", cfg.ShutdownTimeout.String())
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
 
-	if err := application.App.Shutdown(); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
+	if err := shutdown(ctx, application, shutdownTracing); err != nil {
+		slog.Error("Server forced to shutdown", "error", err)
+		cancel()
+		os.Exit(1)
 	}
 
-	log.Println("Server exiting")
+	slog.Info("Server exiting")
 }
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"pre-test-gallery-service/internal/config"
+	"pre-test-gallery-service/internal/handlers"
+	"pre-test-gallery-service/internal/routes"
+	"pre-test-gallery-service/pkg/health"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestShutdownFailsReadinessFirst(t *testing.T) {
+	const drainDelay = 300 * time.Millisecond
+
+	registry := health.NewRegistry(time.Second)
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/readyz", handlers.NewHealthHandler(registry).Readiness)
+	application := &routes.Application{
+		App:          app,
+		HealthChecks: registry,
+		Config:       &config.Config{ShutdownDrainDelay: drainDelay},
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	go func() { _ = app.Listener(listener) }()
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	readyz := func() (int, error) {
+		resp, err := client.Get("http://" + listener.Addr().String() + "/readyz")
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	if status, err := readyz(); err != nil || status != fiber.StatusOK {
+		t.Fatalf("/readyz before shutdown = %d, %v, want 200", status, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- shutdown(ctx, application, func(context.Context) error { return nil }) }()
+
+	// Still accepting connections, but no longer ready
+	time.Sleep(drainDelay / 3)
+	if status, err := readyz(); err != nil || status != fiber.StatusServiceUnavailable {
+		t.Fatalf("/readyz while draining = %d, %v, want 503", status, err)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < drainDelay {
+		t.Errorf("shutdown took %s, want at least the drain delay %s", elapsed, drainDelay)
+	}
+	if _, err := readyz(); err == nil {
+		t.Error("server still accepts connections after shutdown")
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"pre-test-gallery-service/pkg/auth"
+)
+
+func runAPIKeys(ctx context.Context, e *env, args []string) error {
+	return subcommand("apikeys", args, map[string]func([]string) error{
+		"issue": func(args []string) error {
+			flags := newFlagSet("apikeys issue")
+			name := flags.String("name", "", "name of the client the token is for (required)")
+			role := flags.String("role", auth.RoleEditor, "admin, editor or viewer")
+			tenantID := flags.String("tenant", "", "tenant the token is bound to (defaults to DEFAULT_TENANT)")
+			expiresIn := flags.Duration("expires-in", 365*24*time.Hour, "lifetime of the token")
+			if err := parseFlags(flags, args); err != nil {
+				return err
+			}
+			if *name == "" {
+				fmt.Fprintln(os.Stderr, "gallery apikeys issue: -name is required")
+				return errUsage
+			}
+			return issueAPIKey(e, *name, *role, *tenantID, *expiresIn)
+		},
+	})
+}
+
+// issueAPIKey signs an access token like the one handed out after an OIDC
+// login. Tokens are only checked when OIDC login is enabled and cannot be
+// revoked before they expire, other than by rotating JWT_SECRET
+func issueAPIKey(e *env, name, role, tenantID string, expiresIn time.Duration) error {
+	if !auth.IsValidRole(role) {
+		return fmt.Errorf("unknown role %q, want admin, editor or viewer", role)
+	}
+	if expiresIn <= 0 {
+		return errors.New("-expires-in must be positive")
+	}
+	if e.cfg.JWTSecret == "" {
+		return errors.New("JWT_SECRET is not set")
+	}
+	if !e.cfg.OIDCEnabled() {
+		fmt.Fprintln(os.Stderr, "warning: OIDC_ISSUER_URL is not set, so the API does not check tokens and write access is open")
+	}
+
+	if tenantID == "" {
+		tenantID = e.cfg.DefaultTenant
+	}
+	if _, err := e.withTenant(context.Background(), tenantID); err != nil {
+		return err
+	}
+
+	token, expiresAt, err := auth.NewTokenIssuer(e.cfg.JWTSecret, expiresIn).Issue(&auth.Identity{
+		Subject: auth.APIKeySubjectPrefix + name,
+		Name:    name,
+		Role:    role,
+		Tenant:  tenantID,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "issued %s token for %q in tenant %q, expires %s\n", role, name, tenantID, expiresAt.Format(time.RFC3339))
+	fmt.Fprintln(e.stdout, token)
+	return nil
+}
@@ -0,0 +1,239 @@
+// Command gallery runs administrative tasks against the gallery database
+// with the same configuration as the API server.
+//
+//	gallery [-config file] <command> [flags]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"pre-test-gallery-service/internal/config"
+	"pre-test-gallery-service/internal/repository"
+	"pre-test-gallery-service/internal/repository/sqlstore"
+	"pre-test-gallery-service/internal/service"
+	"pre-test-gallery-service/pkg/database"
+	"pre-test-gallery-service/pkg/logger"
+	"pre-test-gallery-service/pkg/tenant"
+)
+
+// errUsage is returned after the usage of a command has been printed
+var errUsage = errors.New("invalid usage")
+
+// errUnavailable marks actions the configured backend does not support
+var errUnavailable = errors.New("not available")
+
+// env is what every command gets: the loaded config and lazily connected
+// database handles
+type env struct {
+	cfg    *config.Config
+	stdout io.Writer
+	client *mongo.Client
+	sqlDB  *sqlstore.DB
+}
+
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, e *env, args []string) error
+}
+
+var commands = []command{
+	{name: "migrate", summary: "apply, revert or list schema migrations", run: runMigrate},
+	{name: "seed", summary: "create the starter tag taxonomy in a tenant", run: runSeed},
+	{name: "tags", summary: "import or export the tags of a tenant", run: runTags},
+	{name: "apikeys", summary: "issue access tokens for automation", run: runAPIKeys},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes a command line and returns the exit code: 2 for usage
+// errors, 1 when the command fails
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("gallery", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configFile := flags.String("config", "", "path to a YAML or TOML config file (defaults to $CONFIG_FILE)")
+	flags.Usage = func() { usage(flags) }
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	cmd, ok := findCommand(flags.Arg(0))
+	if !ok {
+		fmt.Fprintf(stderr, "gallery: unknown command %q\n\n", flags.Arg(0))
+		flags.Usage()
+		return 2
+	}
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	// Logs go to stderr so exports written to stdout stay clean
+	slog.SetDefault(logger.New(stderr, cfg.LogLevel))
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	e := &env{cfg: cfg, stdout: stdout}
+	err = cmd.run(ctx, e, flags.Args()[1:])
+	e.close()
+
+	switch {
+	case errors.Is(err, errUsage):
+		return 2
+	case err != nil:
+		fmt.Fprintf(stderr, "gallery %s: %v\n", cmd.name, err)
+		return 1
+	}
+	return 0
+}
+
+func usage(flags *flag.FlagSet) {
+	fmt.Fprintf(flags.Output(), "Usage: gallery [-config file] <command> [flags]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(flags.Output(), "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(flags.Output(), "\nFlags:\n")
+	flags.PrintDefaults()
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// database connects on first use, so commands that need no database work
+// without one
+func (e *env) database(ctx context.Context) (*mongo.Database, error) {
+	if e.client == nil {
+		client, err := database.ConnectMongoDB(ctx, e.cfg.MongoOptions())
+		if err != nil {
+			return nil, err
+		}
+		e.client = client
+	}
+	return e.client.Database(e.cfg.MongoDBDatabase), nil
+}
+
+// sqlBackend reports whether STORAGE_BACKEND selects postgres or sqlite
+func (e *env) sqlBackend() bool {
+	return e.cfg.StorageBackend != "mongodb"
+}
+
+// sqlDatabase opens the postgres or sqlite database on first use
+func (e *env) sqlDatabase(ctx context.Context) (*sqlstore.DB, error) {
+	if e.sqlDB == nil {
+		db, err := sqlstore.Open(ctx, sqlstore.Dialect(e.cfg.StorageBackend), e.cfg.DatabaseURL)
+		if err != nil {
+			return nil, err
+		}
+		e.sqlDB = db
+	}
+	return e.sqlDB, nil
+}
+
+func (e *env) tagsService(ctx context.Context) (*service.TagsService, error) {
+	if e.sqlBackend() {
+		db, err := e.sqlDatabase(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return service.NewTagsService(sqlstore.NewTagsRepository(db)), nil
+	}
+
+	db, err := e.database(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return service.NewTagsService(repository.NewTagsRepository(db)), nil
+}
+
+func (e *env) close() {
+	if e.client != nil {
+		if err := e.client.Disconnect(context.Background()); err != nil {
+			slog.Error("Failed to disconnect from MongoDB", "error", err)
+		}
+	}
+	if e.sqlDB != nil {
+		if err := e.sqlDB.Close(); err != nil {
+			slog.Error("Failed to close the SQL database", "error", err)
+		}
+	}
+}
+
+// withTenant scopes ctx to the tenant flag, falling back to DEFAULT_TENANT
+func (e *env) withTenant(ctx context.Context, id string) (context.Context, error) {
+	if id == "" {
+		id = e.cfg.DefaultTenant
+	}
+	if id == "" {
+		return nil, errors.New("-tenant is required when DEFAULT_TENANT is not set")
+	}
+	if err := tenant.Validate(id); err != nil {
+		return nil, fmt.Errorf("tenant %q: %w", id, err)
+	}
+	return tenant.WithTenant(ctx, id), nil
+}
+
+// subcommand picks the action of a command such as "tags import"
+func subcommand(name string, args []string, actions map[string]func([]string) error) error {
+	if len(args) > 0 {
+		if action, ok := actions[args[0]]; ok {
+			return action(args[1:])
+		}
+	}
+
+	names := make([]string, 0, len(actions))
+	for action := range actions {
+		names = append(names, action)
+	}
+	slices.Sort(names)
+	fmt.Fprintf(os.Stderr, "Usage: gallery %s <%s> [flags]\n", name, strings.Join(names, "|"))
+	return errUsage
+}
+
+// newFlagSet returns flags that report errors instead of exiting
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet("gallery "+name, flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	return flags
+}
+
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "%s: unexpected arguments %v\n", flags.Name(), flags.Args())
+		return errUsage
+	}
+	return nil
+}
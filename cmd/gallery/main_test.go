@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "sqlite")
+	t.Setenv("DATABASE_URL", filepath.Join(t.TempDir(), "gallery.db"))
+	t.Setenv("DEFAULT_TENANT", "acme")
+	file := writeFile(t, "tags.json", `[{"name":"street"},{"name":"night"}]`)
+	text := writeFile(t, "tags.txt", "street\n")
+
+	// Steps run in order against one database
+	steps := []struct {
+		name    string
+		args    []string
+		want    int
+		wantOut string
+		// exact compares all of stdout to wantOut
+		exact bool
+	}{
+		{name: "no command", args: nil, want: 2},
+		{name: "help", args: []string{"-h"}, want: 0},
+		{name: "unknown command", args: []string{"users", "create-admin"}, want: 2},
+		{name: "unknown flag", args: []string{"-verbose", "seed"}, want: 2},
+		{name: "missing action", args: []string{"tags"}, want: 2},
+		{name: "unknown action", args: []string{"tags", "sync"}, want: 2},
+		{name: "import without file", args: []string{"tags", "import", "-dry-run"}, want: 2},
+		{name: "import unknown flag", args: []string{"tags", "import", "-f", file, "--force"}, want: 2},
+		{name: "import extra arguments", args: []string{"tags", "import", "-f", file, "night"}, want: 2},
+		{name: "import unknown format", args: []string{"tags", "import", "-f", text}, want: 1},
+		{name: "import unknown strategy", args: []string{"tags", "import", "-f", file, "-strategy", "replace"}, want: 1},
+		{name: "invalid tenant", args: []string{"seed", "-tenant", "no spaces"}, want: 1},
+
+		{name: "migrate", args: []string{"migrate", "up"}, want: 0, wantOut: "applied 1 migration(s)"},
+		{name: "dry run", args: []string{"tags", "import", "--tenant", "globex", "--dry-run", "-f", file}, want: 0, wantOut: "dry run: created 2,"},
+		// Still created, so the dry run wrote nothing
+		{name: "import into tenant", args: []string{"tags", "import", "-tenant=globex", "-f", file}, want: 0, wantOut: "created 2,"},
+		{name: "export tenant", args: []string{"tags", "export", "-tenant", "globex", "-format", "csv"}, want: 0, wantOut: "night,"},
+		// DEFAULT_TENANT has no tags
+		{name: "export default tenant", args: []string{"tags", "export", "-format", "csv"}, want: 0, wantOut: "name,created_at\n", exact: true},
+	}
+
+	for _, step := range steps {
+		var stdout, stderr bytes.Buffer
+		if got := run(step.args, &stdout, &stderr); got != step.want {
+			t.Fatalf("%s: gallery %s exited %d, want %d: %s", step.name, strings.Join(step.args, " "), got, step.want, stderr.String())
+		}
+		out := stdout.String()
+		if step.exact && out != step.wantOut || !strings.Contains(out, step.wantOut) {
+			t.Errorf("%s: stdout = %q, want %q", step.name, out, step.wantOut)
+		}
+	}
+}
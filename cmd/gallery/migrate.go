@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"pre-test-gallery-service/internal/migrations"
+	"pre-test-gallery-service/pkg/migrate"
+)
+
+func runMigrate(ctx context.Context, e *env, args []string) error {
+	if e.sqlBackend() {
+		return runSQLMigrate(ctx, e, args)
+	}
+
+	runner := func() (*migrate.Runner, error) {
+		db, err := e.database(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return migrate.New(db, migrations.All(e.cfg.DefaultTenant), e.cfg.MigrationLockTimeout)
+	}
+
+	return subcommand("migrate", args, map[string]func([]string) error{
+		"up": func(args []string) error {
+			if err := parseFlags(newFlagSet("migrate up"), args); err != nil {
+				return err
+			}
+			r, err := runner()
+			if err != nil {
+				return err
+			}
+			applied, err := r.Up(ctx)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(e.stdout, "applied %d migration(s)\n", applied)
+			return nil
+		},
+		"down": func(args []string) error {
+			flags := newFlagSet("migrate down")
+			steps := flags.Int("steps", 1, "number of migrations to revert, newest first")
+			if err := parseFlags(flags, args); err != nil {
+				return err
+			}
+			r, err := runner()
+			if err != nil {
+				return err
+			}
+			reverted, err := r.Down(ctx, *steps)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(e.stdout, "reverted %d migration(s)\n", reverted)
+			return nil
+		},
+		"status": func(args []string) error {
+			if err := parseFlags(newFlagSet("migrate status"), args); err != nil {
+				return err
+			}
+			r, err := runner()
+			if err != nil {
+				return err
+			}
+			statuses, err := r.Status(ctx)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(e.stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "VERSION\tAPPLIED\tDESCRIPTION")
+			for _, status := range statuses {
+				applied := "pending"
+				if status.Applied() {
+					applied = status.AppliedAt.Format(time.RFC3339)
+				}
+				fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, applied, status.Description)
+			}
+			return w.Flush()
+		},
+	})
+}
+
+// runSQLMigrate manages the schema of the postgres and sqlite backends,
+// whose versions are applied forward only
+func runSQLMigrate(ctx context.Context, e *env, args []string) error {
+	return subcommand("migrate", args, map[string]func([]string) error{
+		"up": func(args []string) error {
+			if err := parseFlags(newFlagSet("migrate up"), args); err != nil {
+				return err
+			}
+			db, err := e.sqlDatabase(ctx)
+			if err != nil {
+				return err
+			}
+			applied, err := db.Migrate(ctx)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(e.stdout, "applied %d migration(s)\n", applied)
+			return nil
+		},
+		"down": func(args []string) error {
+			return fmt.Errorf("%w: the %s schema cannot be reverted, restore a backup instead", errUnavailable, e.cfg.StorageBackend)
+		},
+		"status": func(args []string) error {
+			if err := parseFlags(newFlagSet("migrate status"), args); err != nil {
+				return err
+			}
+			db, err := e.sqlDatabase(ctx)
+			if err != nil {
+				return err
+			}
+			statuses, err := db.MigrationStatus(ctx)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(e.stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "VERSION\tAPPLIED\tDESCRIPTION")
+			for _, status := range statuses {
+				applied := "pending"
+				if status.AppliedAt != nil {
+					applied = status.AppliedAt.Format(time.RFC3339)
+				}
+				fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, applied, status.Description)
+			}
+			return w.Flush()
+		},
+	})
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"pre-test-gallery-service/internal/model"
+	"pre-test-gallery-service/internal/service"
+	"pre-test-gallery-service/internal/tagfile"
+	"pre-test-gallery-service/pkg/dto"
+	"pre-test-gallery-service/pkg/i18n"
+	"pre-test-gallery-service/pkg/utils"
+)
+
+// starterTags is the taxonomy created by seed
+var starterTags = []string{
+	"landscape", "portrait", "street", "architecture", "nature",
+	"travel", "food", "black and white", "night", "event",
+}
+
+func runSeed(ctx context.Context, e *env, args []string) error {
+	flags := newFlagSet("seed")
+	tenantID := flags.String("tenant", "", "tenant to seed (defaults to DEFAULT_TENANT)")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	records := make([]dto.TagRecord, 0, len(starterTags))
+	for _, name := range starterTags {
+		records = append(records, dto.TagRecord{Name: name})
+	}
+
+	report, err := importRecords(ctx, e, *tenantID, service.Records(records), service.ImportOptions{Strategy: service.ImportSkip})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(e.stdout, "created %d tag(s), %d already existed\n", report.Count(service.ActionCreated), report.Count(service.ActionSkipped))
+	return nil
+}
+
+func runTags(ctx context.Context, e *env, args []string) error {
+	formats := strings.Join(tagfile.Formats, ", ")
+
+	return subcommand("tags", args, map[string]func([]string) error{
+		"export": func(args []string) error {
+			flags := newFlagSet("tags export")
+			tenantID := flags.String("tenant", "", "tenant to export (defaults to DEFAULT_TENANT)")
+			format := flags.String("format", tagfile.JSON, formats)
+			output := flags.String("o", "", "output file (defaults to stdout)")
+			if err := parseFlags(flags, args); err != nil {
+				return err
+			}
+			return exportTags(ctx, e, *tenantID, *format, *output)
+		},
+		"import": func(args []string) error {
+			flags := newFlagSet("tags import")
+			tenantID := flags.String("tenant", "", "tenant to import into (defaults to DEFAULT_TENANT)")
+			format := flags.String("format", "", formats+" (defaults to the file extension)")
+			input := flags.String("f", "", "file to import, - for stdin")
+			strategy := flags.String("strategy", string(service.ImportSkip), "existing tags: skip, merge (update) or overwrite (update and delete tags missing from the file)")
+			dryRun := flags.Bool("dry-run", false, "report the changes without writing them")
+			if err := parseFlags(flags, args); err != nil {
+				return err
+			}
+			if *input == "" {
+				fmt.Fprintln(os.Stderr, "gallery tags import: -f is required")
+				return errUsage
+			}
+			opts := service.ImportOptions{Strategy: service.ImportStrategy(*strategy), DryRun: *dryRun}
+			return importTags(ctx, e, *tenantID, *format, *input, opts)
+		},
+	})
+}
+
+func exportTags(ctx context.Context, e *env, tenantID, format, output string) (err error) {
+	if !slices.Contains(tagfile.Formats, format) {
+		return fmt.Errorf("unknown format %q", format)
+	}
+
+	ctx, err = e.withTenant(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	tagsService, err := e.tagsService(ctx)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := tagsService.ExportTags(ctx)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var out io.Writer = e.stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		out = f
+	}
+
+	w := bufio.NewWriter(out)
+	encoder, err := tagfile.NewEncoder(w, format)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for cursor.Next(ctx) {
+		var tag model.Tags
+		if err := cursor.Decode(&tag); err != nil {
+			return err
+		}
+		if err := encoder.Encode(tag); err != nil {
+			return err
+		}
+		count++
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "exported %d tag(s)\n", count)
+	return nil
+}
+
+func importTags(ctx context.Context, e *env, tenantID, format, input string, opts service.ImportOptions) error {
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(input), ".")
+	}
+	if !slices.Contains(tagfile.Formats, format) {
+		return fmt.Errorf("unknown format %q, pass -format", format)
+	}
+	switch opts.Strategy {
+	case service.ImportSkip, service.ImportMerge, service.ImportOverwrite:
+	default:
+		return fmt.Errorf("unknown strategy %q, want skip, merge or overwrite", opts.Strategy)
+	}
+
+	var r io.Reader = os.Stdin
+	if input != "-" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	records, err := tagfile.NewDecoder(r, format)
+	if err != nil {
+		return err
+	}
+
+	report, err := importRecords(ctx, e, tenantID, records, opts)
+	if report != nil {
+		printImportReport(e.stdout, report)
+	}
+	return err
+}
+
+func importRecords(ctx context.Context, e *env, tenantID string, records service.TagRecords, opts service.ImportOptions) (*service.ImportReport, error) {
+	ctx, err := e.withTenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	tagsService, err := e.tagsService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return tagsService.ImportTags(ctx, records, opts)
+}
+
+// printImportReport lists every change and invalid record, then the totals
+func printImportReport(w io.Writer, report *service.ImportReport) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, result := range report.Results {
+		if result.Action == service.ActionSkipped || result.Action == service.ActionUnchanged {
+			continue
+		}
+		line := "-"
+		if result.Line > 0 {
+			line = fmt.Sprint(result.Line)
+		}
+		reason := ""
+		for _, fieldErr := range utils.FormatValidationError(result.Err, i18n.English) {
+			reason += fieldErr.Message + " "
+		}
+		if reason == "" && result.Err != nil {
+			reason = result.Err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", line, result.Action, result.Name, strings.TrimSpace(reason))
+	}
+	_ = tw.Flush()
+
+	prefix := ""
+	if report.DryRun {
+		prefix = "dry run: "
+	}
+	fmt.Fprintf(w, "%screated %d, updated %d, unchanged %d, skipped %d, deleted %d, invalid %d\n", prefix,
+		report.Count(service.ActionCreated), report.Count(service.ActionUpdated), report.Count(service.ActionUnchanged),
+		report.Count(service.ActionSkipped), report.Count(service.ActionDeleted), report.Count(service.ActionInvalid))
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"pre-test-gallery-service/internal/config"
+	"pre-test-gallery-service/internal/repository"
+	"pre-test-gallery-service/internal/repository/sqlstore"
+	"pre-test-gallery-service/internal/tagfile"
+	"pre-test-gallery-service/pkg/tenant"
+)
+
+// newTestEnv runs commands against a migrated SQLite database, with acme as
+// DEFAULT_TENANT
+func newTestEnv(t *testing.T) (*env, *bytes.Buffer) {
+	t.Helper()
+
+	ctx := context.Background()
+	db, err := sqlstore.Open(ctx, sqlstore.SQLite, filepath.Join(t.TempDir(), "gallery.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, err := db.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	e := &env{
+		cfg:    &config.Config{StorageBackend: "sqlite", DefaultTenant: "acme"},
+		stdout: &stdout,
+		sqlDB:  db,
+	}
+	t.Cleanup(e.close)
+	return e, &stdout
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func tagNames(t *testing.T, e *env, tenantID string) []string {
+	t.Helper()
+	tags, err := sqlstore.NewTagsRepository(e.sqlDB).FindAll(tenant.WithTenant(context.Background(), tenantID), repository.TagsFilter{})
+	if err != nil {
+		t.Fatalf("FindAll: %v", err)
+	}
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+func TestTagsExportImport(t *testing.T) {
+	ctx := context.Background()
+	e, stdout := newTestEnv(t)
+
+	// The format comes from the extension
+	input := writeFile(t, "tags.csv", "name,created_at\nstreet,2024-01-02T03:04:05Z\nnight,\n")
+	if err := runTags(ctx, e, []string{"import", "-f", input}); err != nil {
+		t.Fatalf("tags import: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "created 2,") {
+		t.Fatalf("import report = %q, want 2 created", stdout)
+	}
+
+	stdout.Reset()
+	if err := runTags(ctx, e, []string{"export", "-format", "ndjson"}); err != nil {
+		t.Fatalf("tags export: %v", err)
+	}
+	exported := stdout.String()
+	records, err := tagfile.Decode(strings.NewReader(exported), tagfile.NDJSON)
+	if err != nil {
+		t.Fatalf("decode export %q: %v", exported, err)
+	}
+	if len(records) != 2 || records[0].Name != "night" || records[1].Name != "street" {
+		t.Fatalf("exported %+v, want night and street by name", records)
+	}
+	if want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC); records[1].CreatedAt == nil || !records[1].CreatedAt.Equal(want) {
+		t.Errorf("street created_at = %v, want %s", records[1].CreatedAt, want)
+	}
+
+	// A dry run into another tenant reports without writing
+	stdout.Reset()
+	file := writeFile(t, "export.ndjson", exported)
+	if err := runTags(ctx, e, []string{"import", "-tenant", "globex", "-f", file, "-dry-run"}); err != nil {
+		t.Fatalf("tags import -dry-run: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "dry run: created 2,") {
+		t.Errorf("dry run report = %q, want 2 created", stdout)
+	}
+	if names := tagNames(t, e, "globex"); len(names) != 0 {
+		t.Errorf("dry run wrote %q", names)
+	}
+}
+
+func TestTagsImportMalformedFile(t *testing.T) {
+	e, stdout := newTestEnv(t)
+
+	// Records are applied as they are read, so the first one stays
+	input := writeFile(t, "tags.json", `[{"name":"street"},{"name":`)
+	err := runTags(context.Background(), e, []string{"import", "-f", input})
+	if err == nil || !strings.Contains(err.Error(), "read json") {
+		t.Fatalf("tags import error = %v, want a read error", err)
+	}
+	if !strings.Contains(stdout.String(), "created 1,") {
+		t.Errorf("report = %q, want the record before the error", stdout)
+	}
+	if names := tagNames(t, e, "acme"); len(names) != 1 || names[0] != "street" {
+		t.Errorf("tags = %q, want street", names)
+	}
+}
+
+func TestSeedIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	e, stdout := newTestEnv(t)
+
+	for _, want := range []string{"created 10 tag(s), 0 already existed", "created 0 tag(s), 10 already existed"} {
+		stdout.Reset()
+		if err := runSeed(ctx, e, nil); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+		if got := strings.TrimSpace(stdout.String()); got != want {
+			t.Errorf("seed output = %q, want %q", got, want)
+		}
+	}
+}
@@ -1,33 +1,460 @@
 package config
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"pre-test-gallery-service/pkg/database"
+
+	"github.com/go-playground/validator/v10"
 	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
 )
 
+// Config is the typed service configuration. Every field is read from the
+// environment variable named by its env tag; the same name in lower case is
+// the key in a YAML or TOML config file. The default tag is the production
+// safe value and devdefault replaces it outside production. Fields tagged
+// secret are redacted when the configuration is printed
 type Config struct {
-	ServerPort  string
-	ServerHost  string
-	ServerState string
+	ServerPort  int    `env:"PORT" default:"8080" validate:"gte=1,lte=65535"`
+	ServerHost  string `env:"HOST" default:"0.0.0.0"`
+	ServerState string `env:"ENV" default:"development"`
+	LogLevel    string `env:"LOG_LEVEL" default:"info" validate:"oneof=debug info warn warning error"`
+	// DefaultLanguage is used when Accept-Language names no supported language
+	DefaultLanguage string `env:"DEFAULT_LANGUAGE" default:"en" validate:"oneof=en th"`
+
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" default:"30s" validate:"gt=0"`
+	// ShutdownDrainDelay keeps serving with /readyz failing before the
+	// server stops accepting connections, so load balancers can notice
+	ShutdownDrainDelay time.Duration `env:"SHUTDOWN_DRAIN_DELAY" default:"5s" devdefault:"0s" validate:"gte=0,ltfield=ShutdownTimeout"`
+	RequestTimeout     time.Duration `env:"REQUEST_TIMEOUT" default:"5s" validate:"gt=0"`
+
+	// StorageBackend selects where metadata lives; postgres and sqlite read
+	// DATABASE_URL, a postgres:// URL or an SQLite file path
+	StorageBackend string `env:"STORAGE_BACKEND" default:"mongodb" validate:"oneof=mongodb postgres sqlite"`
+	DatabaseURL    string `env:"DATABASE_URL" secret:"url" validate:"required_unless=StorageBackend mongodb"`
+
+	MongoDBURI      string `env:"MONGO_URI" secret:"url" validate:"required_if=StorageBackend mongodb"`
+	MongoDBDatabase string `env:"MONGO_DB_NAME" validate:"required_if=StorageBackend mongodb"`
+	// Client tuning; zero values keep the URI options or driver defaults
+	MongoMaxPoolSize            int           `env:"MONGO_MAX_POOL_SIZE" validate:"gte=0"`
+	MongoMinPoolSize            int           `env:"MONGO_MIN_POOL_SIZE" validate:"gte=0"`
+	MongoMaxConnIdleTime        time.Duration `env:"MONGO_MAX_CONN_IDLE_TIME" validate:"gte=0"`
+	MongoConnectTimeout         time.Duration `env:"MONGO_CONNECT_TIMEOUT" default:"10s" validate:"gte=0"`
+	MongoServerSelectionTimeout time.Duration `env:"MONGO_SERVER_SELECTION_TIMEOUT" validate:"gte=0"`
+	MongoTimeout                time.Duration `env:"MONGO_TIMEOUT" validate:"gte=0"`
+	MongoReadPreference         string        `env:"MONGO_READ_PREFERENCE" validate:"omitempty,oneof=primary primaryPreferred secondary secondaryPreferred nearest"`
+	// MongoWriteConcern is majority or a number of acknowledging nodes
+	MongoWriteConcern string   `env:"MONGO_WRITE_CONCERN"`
+	MongoRetryWrites  bool     `env:"MONGO_RETRY_WRITES" default:"true"`
+	MongoRetryReads   bool     `env:"MONGO_RETRY_READS" default:"true"`
+	MongoCompressors  []string `env:"MONGO_COMPRESSORS" validate:"dive,oneof=snappy zlib zstd"`
+	MongoTLS          bool     `env:"MONGO_TLS"`
+	MongoTLSCAFile    string   `env:"MONGO_TLS_CA_FILE"`
+	MongoTLSCertFile  string   `env:"MONGO_TLS_CERT_FILE" validate:"required_with=MongoTLSKeyFile"`
+	MongoTLSKeyFile   string   `env:"MONGO_TLS_KEY_FILE" validate:"required_with=MongoTLSCertFile"`
+	MongoTLSInsecure  bool     `env:"MONGO_TLS_INSECURE"`
+	// MongoConnectRetryTimeout is how long startup waits for MongoDB to come
+	// up, retrying with backoff; 0 fails on the first error
+	MongoConnectRetryTimeout time.Duration `env:"MONGO_CONNECT_RETRY_TIMEOUT" default:"1m" validate:"gte=0"`
+	// MigrateOnStartup applies pending schema migrations before serving
+	MigrateOnStartup     bool          `env:"MIGRATE_ON_STARTUP" default:"true"`
+	MigrationLockTimeout time.Duration `env:"MIGRATION_LOCK_TIMEOUT" default:"2m" validate:"gt=0"`
+
+	JWTSecret    string        `env:"JWT_SECRET" secret:"true" validate:"required_with=OIDCIssuerURL"`
+	JWTExpiresIn time.Duration `env:"JWT_EXPIRES_IN" default:"24h" validate:"gt=0"`
+
+	OIDCIssuerURL    string   `env:"OIDC_ISSUER_URL" validate:"omitempty,url"`
+	OIDCClientID     string   `env:"OIDC_CLIENT_ID" validate:"required_with=OIDCIssuerURL"`
+	OIDCClientSecret string   `env:"OIDC_CLIENT_SECRET" secret:"true"`
+	OIDCRedirectURL  string   `env:"OIDC_REDIRECT_URL" validate:"required_with=OIDCIssuerURL,omitempty,url"`
+	OIDCScopes       []string `env:"OIDC_SCOPES"`
+	OIDCRoleClaim    string   `env:"OIDC_ROLE_CLAIM"`
+	OIDCRoleMapping  string   `env:"OIDC_ROLE_MAPPING"`
+	OIDCDefaultRole  string   `env:"OIDC_DEFAULT_ROLE" default:"viewer" validate:"oneof=admin editor viewer"`
+	OIDCTenantClaim  string   `env:"OIDC_TENANT_CLAIM"`
+
+	TenantHeader  string `env:"TENANT_HEADER" default:"X-Tenant-ID"`
+	DefaultTenant string `env:"DEFAULT_TENANT"`
+
+	RateLimitStore    string `env:"RATE_LIMIT_STORE" default:"memory" validate:"oneof=memory redis"`
+	RateLimitDefault  string `env:"RATE_LIMIT_DEFAULT"`
+	RateLimitPolicies string `env:"RATE_LIMIT_POLICIES"`
+	RedisURL          string `env:"REDIS_URL" secret:"url" validate:"required_if=RateLimitStore redis"`
+
+	TrustedProxies  []string `env:"TRUSTED_PROXIES"`
+	ClientIPHeaders []string `env:"CLIENT_IP_HEADERS"`
+
+	CORSAllowOrigins     []string      `env:"CORS_ALLOW_ORIGINS" devdefault:"*"`
+	CORSAllowMethods     []string      `env:"CORS_ALLOW_METHODS" default:"GET,POST,PUT,DELETE,OPTIONS,PATCH"`
+	CORSAllowHeaders     []string      `env:"CORS_ALLOW_HEADERS" default:"Origin,Authorization,Content-Type,X-Request-ID,Accept-Language"`
+	CORSExposeHeaders    []string      `env:"CORS_EXPOSE_HEADERS" default:"Content-Length,X-Request-ID,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,Retry-After"`
+	CORSAllowCredentials bool          `env:"CORS_ALLOW_CREDENTIALS"`
+	CORSMaxAge           time.Duration `env:"CORS_MAX_AGE" default:"12h" validate:"gte=0"`
+
+	// MetricsToken is the bearer token Prometheus sends to /metrics, which
+	// is not served in production without one
+	MetricsToken string `env:"METRICS_TOKEN" secret:"true"`
+
+	TracesExporter    string  `env:"OTEL_TRACES_EXPORTER" default:"none" validate:"oneof=none stdout otlp"`
+	TracesEndpoint    string  `env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	TracesInsecure    bool    `env:"OTEL_EXPORTER_OTLP_INSECURE"`
+	TracesSampleRatio float64 `env:"OTEL_TRACES_SAMPLER_RATIO" default:"1" validate:"gte=0,lte=1"`
+	TracesServiceName string  `env:"OTEL_SERVICE_NAME" default:"gallery-service"`
+}
+
+const redacted = "[REDACTED]"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Load builds the configuration from the field defaults, then the optional
+// config file (path, or CONFIG_FILE when path is empty), then .env and the
+// process environment, and validates the result. A missing .env file is not
+// an error so containers can inject the environment directly
+func Load(path string) (*Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("load .env: %w", err)
+	}
+
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+
+	values := make(map[string]string)
+	if path != "" {
+		fileValues, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		values = fileValues
+	}
 
-	MongoDBURI      string
-	MongoDBDatabase string
+	// Non-empty environment variables override the file
+	forEachField(func(field reflect.StructField) {
+		name := field.Tag.Get("env")
+		if value := os.Getenv(name); value != "" {
+			values[name] = value
+		}
+	})
+
+	cfg := &Config{}
+	if err := cfg.decode(values); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
 }
 
-func LoadConfig() *Config {
-	err := godotenv.Load()
+// OIDCEnabled reports whether SSO login is configured
+func (c *Config) OIDCEnabled() bool {
+	return c.OIDCIssuerURL != ""
+}
+
+// MongoOptions returns the MongoDB client settings
+func (c *Config) MongoOptions() database.MongoOptions {
+	return database.MongoOptions{
+		URI:                    c.MongoDBURI,
+		MaxPoolSize:            c.MongoMaxPoolSize,
+		MinPoolSize:            c.MongoMinPoolSize,
+		MaxConnIdleTime:        c.MongoMaxConnIdleTime,
+		ConnectTimeout:         c.MongoConnectTimeout,
+		ServerSelectionTimeout: c.MongoServerSelectionTimeout,
+		Timeout:                c.MongoTimeout,
+		ReadPreference:         c.MongoReadPreference,
+		WriteConcern:           c.MongoWriteConcern,
+		DisableRetryWrites:     !c.MongoRetryWrites,
+		DisableRetryReads:      !c.MongoRetryReads,
+		Compressors:            c.MongoCompressors,
+		TLS:                    c.MongoTLS,
+		TLSCAFile:              c.MongoTLSCAFile,
+		TLSCertFile:            c.MongoTLSCertFile,
+		TLSKeyFile:             c.MongoTLSKeyFile,
+		TLSInsecure:            c.MongoTLSInsecure,
+		ConnectRetryTimeout:    c.MongoConnectRetryTimeout,
+	}
+}
+
+// IsProduction reports whether ENV selects the locked down defaults
+func (c *Config) IsProduction() bool {
+	return c.ServerState == "production"
+}
+
+// Validate reports every invalid field using its environment variable name
+func (c *Config) Validate() error {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return field.Tag.Get("env")
+	})
+
+	var errs []error
+	err := validate.Struct(c)
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		for _, e := range validationErrors {
+			errs = append(errs, fmt.Errorf("%s %s", e.Field(), describe(e)))
+		}
+	} else if err != nil {
+		return err
+	}
+
+	if c.MongoWriteConcern != "" && c.MongoWriteConcern != "majority" {
+		if w, err := strconv.Atoi(c.MongoWriteConcern); err != nil || w < 0 {
+			errs = append(errs, fmt.Errorf("MONGO_WRITE_CONCERN must be majority or a number of nodes, got %q", c.MongoWriteConcern))
+		}
+	}
+	if c.MongoMaxPoolSize > 0 && c.MongoMinPoolSize > c.MongoMaxPoolSize {
+		errs = append(errs, errors.New("MONGO_MIN_POOL_SIZE cannot be larger than MONGO_MAX_POOL_SIZE"))
+	}
+	if c.MongoTLSInsecure && c.IsProduction() {
+		errs = append(errs, errors.New("MONGO_TLS_INSECURE cannot be true in production"))
+	}
+
+	if slices.Contains(c.CORSAllowOrigins, "*") {
+		if c.IsProduction() {
+			errs = append(errs, errors.New("CORS_ALLOW_ORIGINS must list explicit origins in production, got \"*\""))
+		}
+		if c.CORSAllowCredentials {
+			errs = append(errs, errors.New("CORS_ALLOW_CREDENTIALS cannot be true when CORS_ALLOW_ORIGINS is \"*\""))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// Print writes the effective configuration in .env format with secrets and
+// URL passwords redacted
+func (c *Config) Print(w io.Writer) error {
+	v := reflect.ValueOf(c).Elem()
+
+	var b strings.Builder
+	forEachField(func(field reflect.StructField) {
+		value := format(v.FieldByIndex(field.Index))
+		if value != "" {
+			switch field.Tag.Get("secret") {
+			case "true":
+				value = redacted
+			case "url":
+				value = redactURL(value)
+			}
+		}
+		fmt.Fprintf(&b, "%s=%s\n", field.Tag.Get("env"), value)
+	})
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// readFile loads a flat YAML or TOML file whose keys are the lower case
+// environment variable names
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	raw := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("config file %s: unsupported format, use .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	known := make(map[string]bool)
+	forEachField(func(field reflect.StructField) {
+		known[field.Tag.Get("env")] = true
+	})
+
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		name := strings.ToUpper(key)
+		if !known[name] {
+			return nil, fmt.Errorf("config file %s: unknown key %q", path, key)
+		}
+		if value == nil {
+			continue
+		}
+
+		if list, ok := value.([]interface{}); ok {
+			items := make([]string, len(list))
+			for i, item := range list {
+				items[i] = fmt.Sprint(item)
+			}
+			values[name] = strings.Join(items, ",")
+		} else {
+			values[name] = fmt.Sprint(value)
+		}
+	}
+	return values, nil
+}
+
+// decode sets every field from values, falling back to its default for the
+// environment selected by ENV
+func (c *Config) decode(values map[string]string) error {
+	v := reflect.ValueOf(c).Elem()
+	production := values["ENV"] == "production"
+
+	var errs []error
+	forEachField(func(field reflect.StructField) {
+		name := field.Tag.Get("env")
+		raw, ok := values[name]
+		if !ok {
+			raw = field.Tag.Get("default")
+			if devDefault, hasDevDefault := field.Tag.Lookup("devdefault"); hasDevDefault && !production {
+				raw = devDefault
+			}
+		}
+		if raw == "" {
+			return
+		}
+		if err := set(v.FieldByIndex(field.Index), raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	})
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func set(field reflect.Value, raw string) error {
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		field.SetInt(int64(d))
+		return nil
 	}
 
-	return &Config{
-		ServerPort:  os.Getenv("PORT"),
-		ServerHost:  os.Getenv("HOST"),
-		ServerState: os.Getenv("ENV"),
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		field.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		field.Set(reflect.ValueOf(splitList(raw)))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+func format(field reflect.Value) string {
+	if field.Type() == durationType {
+		return time.Duration(field.Int()).String()
+	}
+	if field.Kind() == reflect.Slice {
+		return strings.Join(field.Interface().([]string), ",")
+	}
+	return fmt.Sprint(field.Interface())
+}
+
+func describe(e validator.FieldError) string {
+	switch e.Tag() {
+	case "required":
+		return "is required"
+	case "required_with":
+		return fmt.Sprintf("is required when %s is set", envName(e.Param()))
+	case "required_if":
+		field, value, _ := strings.Cut(e.Param(), " ")
+		return fmt.Sprintf("is required when %s is %s", envName(field), value)
+	case "required_unless":
+		field, value, _ := strings.Cut(e.Param(), " ")
+		return fmt.Sprintf("is required when %s is not %s", envName(field), value)
+	case "oneof":
+		return fmt.Sprintf("must be one of %s, got %q", strings.ReplaceAll(e.Param(), " ", ", "), e.Value())
+	case "url":
+		return fmt.Sprintf("must be a URL, got %q", e.Value())
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", e.Param())
+	case "gte":
+		return fmt.Sprintf("must be at least %s", e.Param())
+	case "lte":
+		return fmt.Sprintf("must be at most %s", e.Param())
+	case "ltfield":
+		return fmt.Sprintf("must be less than %s", envName(e.Param()))
+	default:
+		return fmt.Sprintf("failed %q validation", e.Tag())
+	}
+}
+
+// envName maps a Config field name to its environment variable
+func envName(fieldName string) string {
+	if field, ok := reflect.TypeOf(Config{}).FieldByName(fieldName); ok {
+		return field.Tag.Get("env")
+	}
+	return fieldName
+}
+
+func redactURL(raw string) string {
+	scheme, rest, ok := strings.Cut(raw, "://")
+	if !ok {
+		return raw
+	}
+	userinfo, host, ok := strings.Cut(rest, "@")
+	if !ok {
+		return raw
+	}
+	user, _, ok := strings.Cut(userinfo, ":")
+	if !ok {
+		return raw
+	}
+	return scheme + "://" + user + ":" + redacted + "@" + host
+}
+
+func forEachField(fn func(field reflect.StructField)) {
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		fn(t.Field(i))
+	}
+}
 
-		MongoDBURI:      os.Getenv("MONGO_URI"),
-		MongoDBDatabase: os.Getenv("MONGO_DB_NAME"),
+// splitList splits a comma separated value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
+	return items
 }
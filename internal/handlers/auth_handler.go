@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"pre-test-gallery-service/internal/service"
+	"pre-test-gallery-service/pkg/apperror"
+	"pre-test-gallery-service/pkg/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	oidcStateCookie = "oidc_state"
+	oidcNonceCookie = "oidc_nonce"
+	oidcCookieTTL   = 10 * time.Minute
+)
+
+var errMissingNonce = errors.New("oidc nonce cookie is missing")
+
+var (
+	ErrInvalidLoginState = apperror.New(apperror.KindInvalid, "AUTH_INVALID_STATE", "Invalid login state")
+	ErrAuthCodeRequired  = apperror.New(apperror.KindInvalid, "AUTH_CODE_REQUIRED", "Authorization code is required")
+)
+
+type AuthHandler struct {
+	authService  *service.AuthService
+	secureCookie bool
+}
+
+func NewAuthHandler(authService *service.AuthService, secureCookie bool) *AuthHandler {
+	return &AuthHandler{
+		authService:  authService,
+		secureCookie: secureCookie,
+	}
+}
+
+// @Summary Start OIDC login
+// @Description Redirect to the identity provider
+// @Tags auth
+// @Success 302
+// @Router /auth/oidc/login [get]
+func (h *AuthHandler) OIDCLogin(c *fiber.Ctx) error {
+	authURL, state, nonce, err := h.authService.LoginURL()
+	if err != nil {
+		return err
+	}
+
+	h.setCookie(c, oidcStateCookie, state, time.Now().Add(oidcCookieTTL))
+	h.setCookie(c, oidcNonceCookie, nonce, time.Now().Add(oidcCookieTTL))
+
+	return c.Redirect(authURL, fiber.StatusFound)
+}
+
+// @Summary OIDC login callback
+// @Description Exchange the authorization code and issue an access token
+// @Tags auth
+// @Produce json
+// @Param code query string true "Authorization code"
+// @Param state query string true "OAuth2 state"
+// @Success 200 {object} dto.AuthResponse
+// @Failure 400 {object} utils.ProblemDetails "AUTH_INVALID_STATE or AUTH_CODE_REQUIRED"
+// @Failure 401 {object} utils.ProblemDetails "AUTH_LOGIN_FAILED"
+// @Router /auth/oidc/callback [get]
+func (h *AuthHandler) OIDCCallback(c *fiber.Ctx) error {
+	// The IdP error comes from the query string, so it is only logged
+	if errParam := c.Query("error"); errParam != "" {
+		return service.ErrLoginFailed.Wrap(fmt.Errorf("identity provider error %q: %q", errParam, c.Query("error_description")))
+	}
+
+	state := c.Cookies(oidcStateCookie)
+	nonce := c.Cookies(oidcNonceCookie)
+	h.setCookie(c, oidcStateCookie, "", time.Unix(0, 0))
+	h.setCookie(c, oidcNonceCookie, "", time.Unix(0, 0))
+
+	if state == "" || c.Query("state") != state {
+		return ErrInvalidLoginState
+	}
+	// An empty nonce would match an ID token issued without one
+	if nonce == "" {
+		return service.ErrLoginFailed.Wrap(errMissingNonce)
+	}
+
+	code := c.Query("code")
+	if code == "" {
+		return ErrAuthCodeRequired
+	}
+
+	res, err := h.authService.CompleteLogin(c.UserContext(), code, nonce)
+	if err != nil {
+		return err
+	}
+
+	return utils.SendSuccess(c, fiber.StatusOK, res)
+}
+
+func (h *AuthHandler) setCookie(c *fiber.Ctx, name, value string, expires time.Time) {
+	c.Cookie(&fiber.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Expires:  expires,
+		HTTPOnly: true,
+		Secure:   h.secureCookie,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
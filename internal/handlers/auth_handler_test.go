@@ -0,0 +1,121 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"pre-test-gallery-service/internal/config"
+	"pre-test-gallery-service/internal/handlers"
+	"pre-test-gallery-service/internal/repository/memory"
+	"pre-test-gallery-service/internal/routes"
+	"pre-test-gallery-service/internal/service"
+	"pre-test-gallery-service/pkg/auth"
+	"pre-test-gallery-service/pkg/auth/oidctest"
+	"pre-test-gallery-service/pkg/health"
+	"pre-test-gallery-service/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// newAuthTestApp wires the real routes with OIDC login against idp
+func newAuthTestApp(t *testing.T, idp *oidctest.IdP) (*fiber.App, *auth.OIDCProvider) {
+	t.Helper()
+
+	provider, err := auth.NewOIDCProvider(context.Background(), auth.OIDCConfig{
+		IssuerURL:    idp.Issuer(),
+		ClientID:     idp.ClientID,
+		ClientSecret: idp.ClientSecret,
+		RedirectURL:  "http://gallery.test/api/v1/auth/oidc/callback",
+		RoleMapper:   auth.RoleMapper{DefaultRole: auth.RoleViewer},
+	})
+	if err != nil {
+		t.Fatalf("NewOIDCProvider: %v", err)
+	}
+	tokenIssuer := auth.NewTokenIssuer("test-secret", time.Hour)
+
+	app := fiber.New(fiber.Config{ErrorHandler: utils.ErrorHandler})
+	application := &routes.Application{
+		App:            app,
+		TagsHandler:    handlers.NewTagsHandler(service.NewTagsService(memory.NewTagsRepository())),
+		AuthHandler:    handlers.NewAuthHandler(service.NewAuthService(provider, tokenIssuer), false),
+		HealthHandler:  handlers.NewHealthHandler(health.NewRegistry(time.Second)),
+		TokenIssuer:    tokenIssuer,
+		RequestTimeout: 5 * time.Second,
+		Config:         &config.Config{TenantHeader: "X-Tenant-ID"},
+	}
+	application.SetupRoutes()
+	return app, provider
+}
+
+func TestOIDCCallbackRejectsLogin(t *testing.T) {
+	idp, err := oidctest.NewIdP("gallery", "secret")
+	if err != nil {
+		t.Fatalf("NewIdP: %v", err)
+	}
+	t.Cleanup(idp.Close)
+	app, provider := newAuthTestApp(t, idp)
+
+	// A code issued for a login without nonce, as an attacker could obtain
+	redirect, err := idp.Authorize(provider.AuthCodeURL("state-1", ""))
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	code := redirect.Query().Get("code")
+
+	tests := []struct {
+		name     string
+		query    string
+		cookies  string
+		wantCode string
+	}{
+		{
+			name:     "provider error is not echoed",
+			query:    "error=access_denied%3Cscript%3E&error_description=Call+0800+now",
+			wantCode: "AUTH_LOGIN_FAILED",
+		},
+		{
+			name:     "missing nonce cookie",
+			query:    "state=state-1&code=" + code,
+			cookies:  "oidc_state=state-1",
+			wantCode: "AUTH_LOGIN_FAILED",
+		},
+		{
+			name:     "empty nonce cookie",
+			query:    "state=state-1&code=" + code,
+			cookies:  "oidc_state=state-1; oidc_nonce=",
+			wantCode: "AUTH_LOGIN_FAILED",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodGet, "/api/v1/auth/oidc/callback?"+tt.query, nil)
+			if tt.cookies != "" {
+				req.Header.Set(fiber.HeaderCookie, tt.cookies)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("app.Test: %v", err)
+			}
+			defer resp.Body.Close()
+
+			var problem utils.ProblemDetails
+			if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if resp.StatusCode != fiber.StatusUnauthorized || problem.Code != tt.wantCode {
+				t.Fatalf("got %d %+v, want 401 %s", resp.StatusCode, problem, tt.wantCode)
+			}
+			if problem.Detail != "Login failed" {
+				t.Errorf("detail = %q, want the fixed message", problem.Detail)
+			}
+			if strings.Contains(problem.Detail, "script") || strings.Contains(problem.Detail, "0800") {
+				t.Errorf("detail %q carries text from the query string", problem.Detail)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"pre-test-gallery-service/pkg/health"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type HealthHandler struct {
+	registry *health.Registry
+}
+
+func NewHealthHandler(registry *health.Registry) *HealthHandler {
+	return &HealthHandler{
+		registry: registry,
+	}
+}
+
+// Liveness reports that the process is running (GET /healthz)
+func (h *HealthHandler) Liveness(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(health.Report{Status: health.StatusUp})
+}
+
+// Readiness checks every dependency needed to serve traffic and answers
+// 503 with per-dependency detail when one is down (GET /readyz)
+func (h *HealthHandler) Readiness(c *fiber.Ctx) error {
+	report := h.registry.Run(c.UserContext())
+	if report.Status != health.StatusUp {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(report)
+	}
+	return c.Status(fiber.StatusOK).JSON(report)
+}
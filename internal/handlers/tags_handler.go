@@ -1,16 +1,37 @@
 package handlers
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"pre-test-gallery-service/internal/model"
+	"pre-test-gallery-service/internal/repository"
 	"pre-test-gallery-service/internal/service"
+	"pre-test-gallery-service/internal/tagfile"
+	"pre-test-gallery-service/pkg/apperror"
 	"pre-test-gallery-service/pkg/dto"
+	"pre-test-gallery-service/pkg/logger"
 	"pre-test-gallery-service/pkg/utils"
 	"time"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
 )
 
+var ErrImportFormatRequired = apperror.New(apperror.KindInvalid, "TAG_IMPORT_FORMAT_REQUIRED", "Pass format=json, ndjson or csv or a matching Content-Type")
+
+// exportTimeout bounds streaming an export, which outlives REQUEST_TIMEOUT
+const exportTimeout = 10 * time.Minute
+
+// ImportTimeout bounds an import, which the router runs instead of
+// REQUEST_TIMEOUT as it reads the file while applying it
+const ImportTimeout = 10 * time.Minute
+
+// exportFlushEvery is how many tags are buffered before they are sent
+const exportFlushEvery = 100
+
 type TagsHandler struct {
 	tagsService *service.TagsService
 }
@@ -22,83 +43,263 @@ func NewTagsHandler(tagsService *service.TagsService) *TagsHandler {
 }
 
 // @Summary Get all tags
-// @Description Get all tags
+// @Description Get the tags of the workspace, optionally filtered. Times are RFC 3339; from is inclusive and to exclusive
 // @Tags tags
 // @Produce json
+// @Param X-Tenant-ID header string false "Workspace ID"
+// @Param name query string false "Exact name"
+// @Param slug query string false "Name slug, e.g. black-and-white matches Black and White"
+// @Param prefix query string false "Name prefix, case sensitive"
+// @Param created_from query string false "Created at or after" format(date-time)
+// @Param created_to query string false "Created before" format(date-time)
+// @Param updated_from query string false "Updated at or after" format(date-time)
+// @Param updated_to query string false "Updated before" format(date-time)
 // @Success 200 {object} []model.Tags
+// @Failure 400 {object} utils.ProblemDetails
+// @Failure 500 {object} utils.ProblemDetails
 // @Router /tags [get]
 func (h *TagsHandler) GetAllTags(c *fiber.Ctx) error {
-	tags, err := h.tagsService.GetAllTags(c.Context())
+	var query dto.TagListQuery
+	if err := utils.BindQuery(c, &query); err != nil {
+		return err
+	}
+
+	tags, err := h.tagsService.GetAllTags(c.UserContext(), tagsFilter(query))
 	if err != nil {
-		return utils.SendError(c, fiber.StatusNotFound, "Tags not found")
+		return err
 	}
 	return utils.SendSuccess(c, fiber.StatusOK, tags)
 }
 
+// tagsFilter converts a validated list query
+func tagsFilter(query dto.TagListQuery) repository.TagsFilter {
+	return repository.TagsFilter{
+		Name:       query.Name,
+		Slug:       query.Slug,
+		NamePrefix: query.Prefix,
+		CreatedAt:  repository.TimeRange{From: parseTime(query.CreatedFrom), To: parseTime(query.CreatedTo)},
+		UpdatedAt:  repository.TimeRange{From: parseTime(query.UpdatedFrom), To: parseTime(query.UpdatedTo)},
+	}
+}
+
+// parseTime returns the zero time for "", which leaves the bound open
+func parseTime(value string) time.Time {
+	t, _ := time.Parse(time.RFC3339, value)
+	return t
+}
+
 // @Summary Create a new tag
 // @Description Create a new tag
 // @Tags tags
 // @Accept json
 // @Produce json
+// @Param X-Tenant-ID header string false "Workspace ID"
 // @Param tags body dto.TagsRequest true "Tags request"
 // @Success 200 {object} model.Tags
+// @Failure 400 {object} utils.ProblemDetails
+// @Failure 409 {object} utils.ProblemDetails "TAG_ALREADY_EXISTS"
 // @Router /tags [post]
 func (h *TagsHandler) CreateTags(c *fiber.Ctx) error {
 	var req dto.TagsRequest
+	if err := utils.Bind(c, &req); err != nil {
+		return err
+	}
 
-	if err := c.BodyParser(&req); err != nil {
-		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	tag, err := h.tagsService.CreateTags(c.UserContext(), req)
+	if err != nil {
+		return err
 	}
 
-	if err := utils.ValidateStruct(&req); err != nil {
-		return utils.SendValidationError(c, err)
+	return utils.SendSuccess(c, fiber.StatusOK, tag)
+}
+
+// @Summary Delete a tag
+// @Description Delete a tag
+// @Tags tags
+// @Produce json
+// @Param X-Tenant-ID header string false "Workspace ID"
+// @Param tag path string true "Tag name"
+// @Success 200 {object} nil
+// @Failure 404 {object} utils.ProblemDetails "TAG_NOT_FOUND"
+// @Failure 400 {object} utils.ProblemDetails
+// @Router /tags/{tag} [delete]
+func (h *TagsHandler) DeleteTags(c *fiber.Ctx) error {
+	var params dto.TagParams
+	if err := utils.Bind(c, &params); err != nil {
+		return err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	if err := h.tagsService.DeleteTagsByName(c.UserContext(), params.Name); err != nil {
+		return err
+	}
 
-	existingTag, err := h.tagsService.FindOneTags(ctx, bson.M{"name": req.Name})
-	if err != nil {
-		return utils.SendError(c, fiber.StatusBadRequest, err.Error())
+	return utils.SendSuccess(c, fiber.StatusOK, nil, "Tags deleted successfully")
+}
+
+// @Summary Export tags
+// @Description Stream every tag of the workspace ordered by name as a JSON array, NDJSON or CSV
+// @Tags tags
+// @Produce json,text/csv,application/x-ndjson
+// @Param X-Tenant-ID header string false "Workspace ID"
+// @Param format query string false "File format" Enums(json, ndjson, csv) default(json)
+// @Success 200 {array} dto.TagRecord
+// @Failure 400 {object} utils.ProblemDetails
+// @Failure 500 {object} utils.ProblemDetails
+// @Router /tags/export [get]
+func (h *TagsHandler) ExportTags(c *fiber.Ctx) error {
+	var query dto.TagExportQuery
+	if err := utils.BindQuery(c, &query); err != nil {
+		return err
+	}
+	format := query.Format
+	if format == "" {
+		format = tagfile.JSON
 	}
 
-	if existingTag != nil {
-		return utils.SendError(c, fiber.StatusBadRequest, "Tags already exist")
+	// The body is written after the handler returns, when the request
+	// context has already ended; keep its values (tenant, logger, trace).
+	// The cursor is opened with it too, as database/sql closes rows when
+	// the context they were queried with ends
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(c.UserContext()), exportTimeout)
+
+	cursor, err := h.tagsService.ExportTags(ctx)
+	if err != nil {
+		cancel()
+		return err
 	}
 
-	tag, err := h.tagsService.CreateTags(ctx, req)
+	tenantID, _ := c.Locals("tenant").(string)
+	c.Set(fiber.HeaderContentType, tagfile.ContentType(format))
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="tags-%s.%s"`, tenantID, format))
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+		defer cursor.Close(ctx)
+
+		if err := writeTags(ctx, w, format, cursor); err != nil {
+			logger.FromContext(ctx).Error("Tag export failed", "error", err)
+		}
+	})
+	return nil
+}
+
+// writeTags flushes every few tags so the client receives the export while
+// the cursor is still being read
+func writeTags(ctx context.Context, w *bufio.Writer, format string, cursor repository.TagsCursor) error {
+	encoder, err := tagfile.NewEncoder(w, format)
 	if err != nil {
-		return utils.SendError(c, fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 
-	return utils.SendSuccess(c, fiber.StatusOK, tag)
+	for n := 1; cursor.Next(ctx); n++ {
+		var tag model.Tags
+		if err := cursor.Decode(&tag); err != nil {
+			return err
+		}
+		if err := encoder.Encode(tag); err != nil {
+			return err
+		}
+		if n%exportFlushEvery == 0 {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	return w.Flush()
 }
 
-// @Summary Delete a tag
-// @Description Delete a tag
+// @Summary Import tags
+// @Description Import a JSON array, NDJSON or CSV file (header with a name column, created_at optional).
+// @Description skip only creates missing tags, merge also updates existing ones and overwrite also deletes tags missing from the file.
+// @Description Records are applied as they are read, so a malformed record fails the request after the records before it.
 // @Tags tags
+// @Accept json,text/csv,application/x-ndjson
 // @Produce json
-// @Param id path string true "Tag ID"
-// @Success 200 {object} nil
-// @Router /tags/{id} [delete]
-func (h *TagsHandler) DeleteTags(c *fiber.Ctx) error {
-	tag := c.Params("tag")
+// @Param X-Tenant-ID header string false "Workspace ID"
+// @Param format query string false "File format, defaults to the Content-Type" Enums(json, ndjson, csv)
+// @Param strategy query string false "What happens to existing tags" Enums(skip, merge, overwrite) default(skip)
+// @Param dry_run query bool false "Report the changes without writing them"
+// @Param file body []dto.TagRecord true "Tags"
+// @Success 200 {object} dto.TagImportReport
+// @Failure 400 {object} utils.ProblemDetails
+// @Failure 500 {object} utils.ProblemDetails
+// @Router /tags/import [post]
+func (h *TagsHandler) ImportTags(c *fiber.Ctx) error {
+	var query dto.TagImportQuery
+	if err := utils.BindQuery(c, &query); err != nil {
+		return err
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	format := query.Format
+	if format == "" {
+		format = tagfile.FormatFromContentType(c.Get(fiber.HeaderContentType))
+	}
+	if format == "" {
+		return ErrImportFormatRequired
+	}
 
-	tagRes, err := h.tagsService.FindOneTags(ctx, bson.M{"name": tag})
+	// The body is read as records are applied; it is only in memory when
+	// the app does not stream request bodies. A failed import leaves the
+	// rest of it unread, so the connection is not reused
+	body := c.Context().RequestBodyStream()
+	if body == nil {
+		body = bytes.NewReader(c.Body())
+	} else {
+		c.Context().SetConnectionClose()
+	}
+	records, err := tagfile.NewDecoder(body, format)
 	if err != nil {
-		return utils.SendError(c, fiber.StatusBadRequest, err.Error())
+		return apperror.ErrInvalidRequestBody.Wrap(err)
 	}
 
-	if tagRes == nil {
-		return utils.SendError(c, fiber.StatusBadRequest, "Tags not found")
+	report, err := h.tagsService.ImportTags(c.UserContext(), records, service.ImportOptions{
+		Strategy: service.ImportStrategy(query.Strategy),
+		DryRun:   query.DryRun,
+	})
+	var decodeErr *tagfile.DecodeError
+	if errors.As(err, &decodeErr) {
+		return apperror.ErrInvalidRequestBody.Wrap(err)
 	}
+	if err != nil {
+		return err
+	}
+
+	return utils.SendSuccess(c, fiber.StatusOK, importReport(report, utils.Language(c)))
+}
 
-	if err := h.tagsService.DeleteTags(ctx, tagRes.ID); err != nil {
-		return utils.SendError(c, fiber.StatusInternalServerError, err.Error())
+// importReport describes the outcome of every record, with the reasons of
+// invalid ones in lang
+func importReport(report *service.ImportReport, lang string) dto.TagImportReport {
+	res := dto.TagImportReport{
+		DryRun:    report.DryRun,
+		Strategy:  string(report.Strategy),
+		Created:   report.Count(service.ActionCreated),
+		Updated:   report.Count(service.ActionUpdated),
+		Unchanged: report.Count(service.ActionUnchanged),
+		Skipped:   report.Count(service.ActionSkipped),
+		Deleted:   report.Count(service.ActionDeleted),
+		Invalid:   report.Count(service.ActionInvalid),
+		Items:     make([]dto.TagImportItem, 0, len(report.Results)),
 	}
 
-	return utils.SendSuccess(c, fiber.StatusOK, nil, "Tags deleted successfully")
+	for _, result := range report.Results {
+		item := dto.TagImportItem{Line: result.Line, Name: result.Name, Action: string(result.Action)}
+
+		var validationErrors validator.ValidationErrors
+		var appErr *apperror.Error
+		switch {
+		case errors.As(result.Err, &validationErrors):
+			item.Errors = utils.FormatValidationError(validationErrors, lang)
+		case errors.As(result.Err, &appErr):
+			item.Errors = []apperror.FieldError{{Field: "name", Rule: appErr.Code, Message: utils.Message(lang, appErr)}}
+		}
+		res.Items = append(res.Items, item)
+	}
+	return res
 }
@@ -0,0 +1,417 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"pre-test-gallery-service/internal/config"
+	"pre-test-gallery-service/internal/handlers"
+	"pre-test-gallery-service/internal/repository"
+	"pre-test-gallery-service/internal/repository/memory"
+	"pre-test-gallery-service/internal/repository/sqlstore"
+	"pre-test-gallery-service/internal/routes"
+	"pre-test-gallery-service/internal/service"
+	"pre-test-gallery-service/pkg/dto"
+	"pre-test-gallery-service/pkg/health"
+	"pre-test-gallery-service/pkg/middleware"
+	"pre-test-gallery-service/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// newTestApp wires the real routes to in-memory repositories; requests
+// without a tenant header are rejected
+func newTestApp(t *testing.T) *fiber.App {
+	t.Helper()
+	return newTestAppWith(t, memory.NewTagsRepository(), time.Second)
+}
+
+// testBodyLimit is small so that imports larger than BodyLimit are cheap
+const testBodyLimit = 4 * 1024
+
+func newTestAppWith(t *testing.T, tags repository.TagsRepository, requestTimeout time.Duration) *fiber.App {
+	t.Helper()
+
+	if err := utils.SetupValidator(); err != nil {
+		t.Fatalf("SetupValidator: %v", err)
+	}
+
+	app := fiber.New(fiber.Config{
+		ErrorHandler:      utils.ErrorHandler,
+		UnescapePath:      true,
+		StreamRequestBody: true,
+		BodyLimit:         testBodyLimit,
+	})
+	app.Use(middleware.Language("en"))
+
+	application := &routes.Application{
+		App:            app,
+		TagsHandler:    handlers.NewTagsHandler(service.NewTagsService(tags)),
+		HealthHandler:  handlers.NewHealthHandler(health.NewRegistry(time.Second)),
+		RequestTimeout: requestTimeout,
+		Config:         &config.Config{TenantHeader: "X-Tenant-ID"},
+	}
+	application.SetupRoutes()
+	return app
+}
+
+type testRequest struct {
+	method, target, tenant string
+	contentType, body      string
+}
+
+type testResponse struct {
+	status  int
+	header  map[string]string
+	body    []byte
+	problem utils.ProblemDetails
+}
+
+func do(t *testing.T, app *fiber.App, r testRequest) testResponse {
+	t.Helper()
+
+	req := httptest.NewRequest(r.method, r.target, strings.NewReader(r.body))
+	if r.tenant != "" {
+		req.Header.Set("X-Tenant-ID", r.tenant)
+	}
+	contentType := r.contentType
+	if contentType == "" && r.body != "" {
+		contentType = fiber.MIMEApplicationJSON
+	}
+	if contentType != "" {
+		req.Header.Set(fiber.HeaderContentType, contentType)
+	}
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", r.method, r.target, err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("%s %s: read body: %v", r.method, r.target, err)
+	}
+
+	res := testResponse{status: resp.StatusCode, body: body, header: map[string]string{}}
+	for key := range resp.Header {
+		res.header[key] = resp.Header.Get(key)
+	}
+	if resp.Header.Get(fiber.HeaderContentType) == utils.MIMEProblemJSON {
+		if err := json.Unmarshal(body, &res.problem); err != nil {
+			t.Fatalf("%s %s: decode problem: %v", r.method, r.target, err)
+		}
+	}
+	return res
+}
+
+func TestTagsRoutes(t *testing.T) {
+	thai := url.PathEscape("ทะเล")
+
+	// Steps run in order against one app, so later steps see earlier writes
+	steps := []struct {
+		name       string
+		req        testRequest
+		wantStatus int
+		wantCode   string
+		wantBody   string
+	}{
+		{name: "empty list", req: testRequest{method: "GET", target: "/api/v1/tags", tenant: "acme"}, wantStatus: 200, wantBody: `"data":[]`},
+		{name: "create", req: testRequest{method: "POST", target: "/api/v1/tags", tenant: "acme", body: `{"name":"street"}`}, wantStatus: 200, wantBody: `"name":"street"`},
+		{name: "create thai", req: testRequest{method: "POST", target: "/api/v1/tags", tenant: "acme", body: `{"name":"ทะเล"}`}, wantStatus: 200},
+		{name: "duplicate", req: testRequest{method: "POST", target: "/api/v1/tags", tenant: "acme", body: `{"name":"street"}`}, wantStatus: 409, wantCode: "TAG_ALREADY_EXISTS"},
+		{name: "invalid name", req: testRequest{method: "POST", target: "/api/v1/tags", tenant: "acme", body: `{"name":"a/b"}`}, wantStatus: 400, wantCode: "VALIDATION_FAILED"},
+		{name: "reserved name", req: testRequest{method: "POST", target: "/api/v1/tags", tenant: "acme", body: `{"name":"None"}`}, wantStatus: 400, wantCode: "VALIDATION_FAILED"},
+		{name: "malformed body", req: testRequest{method: "POST", target: "/api/v1/tags", tenant: "acme", body: `{"name":`}, wantStatus: 400, wantCode: "INVALID_REQUEST_BODY"},
+		{name: "missing tenant", req: testRequest{method: "GET", target: "/api/v1/tags"}, wantStatus: 400, wantCode: "TENANT_REQUIRED"},
+		{name: "invalid tenant", req: testRequest{method: "GET", target: "/api/v1/tags", tenant: "a b"}, wantStatus: 400, wantCode: "INVALID_TENANT"},
+		{name: "other tenant sees nothing", req: testRequest{method: "GET", target: "/api/v1/tags", tenant: "other"}, wantStatus: 200, wantBody: `"data":[]`},
+		{name: "delete in other tenant", req: testRequest{method: "DELETE", target: "/api/v1/tags/street", tenant: "other"}, wantStatus: 404, wantCode: "TAG_NOT_FOUND"},
+		{name: "delete percent-encoded", req: testRequest{method: "DELETE", target: "/api/v1/tags/" + thai, tenant: "acme"}, wantStatus: 200},
+		{name: "delete again", req: testRequest{method: "DELETE", target: "/api/v1/tags/" + thai, tenant: "acme"}, wantStatus: 404, wantCode: "TAG_NOT_FOUND"},
+		// Names are only validated on create, so older ones stay deletable
+		{name: "delete name create rejects", req: testRequest{method: "DELETE", target: "/api/v1/tags/" + url.PathEscape("a;b"), tenant: "acme"}, wantStatus: 404, wantCode: "TAG_NOT_FOUND"},
+		{name: "list after delete", req: testRequest{method: "GET", target: "/api/v1/tags", tenant: "acme"}, wantStatus: 200, wantBody: `"name":"street"`},
+		{name: "unknown route", req: testRequest{method: "GET", target: "/api/v1/nope", tenant: "acme"}, wantStatus: 404, wantCode: "NOT_FOUND"},
+	}
+
+	app := newTestApp(t)
+	for _, step := range steps {
+		res := do(t, app, step.req)
+		if res.status != step.wantStatus {
+			t.Fatalf("%s: status = %d, want %d: %s", step.name, res.status, step.wantStatus, res.body)
+		}
+		if res.problem.Code != step.wantCode {
+			t.Errorf("%s: code = %q, want %q", step.name, res.problem.Code, step.wantCode)
+		}
+		if step.wantBody != "" && !strings.Contains(string(res.body), step.wantBody) {
+			t.Errorf("%s: body %s does not contain %s", step.name, res.body, step.wantBody)
+		}
+	}
+}
+
+func TestTagsExportImport(t *testing.T) {
+	app := newTestApp(t)
+	for _, name := range []string{"street", "night", "ทะเล"} {
+		do(t, app, testRequest{method: "POST", target: "/api/v1/tags", tenant: "staging", body: `{"name":"` + name + `"}`})
+	}
+
+	tests := []struct {
+		format      string
+		contentType string
+		wantLines   int
+	}{
+		{format: "json", contentType: "application/json", wantLines: 5},
+		{format: "ndjson", contentType: "application/x-ndjson", wantLines: 3},
+		{format: "csv", contentType: "text/csv; charset=utf-8", wantLines: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			export := do(t, app, testRequest{method: "GET", target: "/api/v1/tags/export?format=" + tt.format, tenant: "staging"})
+			if export.status != 200 || export.header[fiber.HeaderContentType] != tt.contentType {
+				t.Fatalf("export: status %d, Content-Type %q: %s", export.status, export.header[fiber.HeaderContentType], export.body)
+			}
+			if lines := strings.Count(string(export.body), "\n"); lines != tt.wantLines {
+				t.Fatalf("export has %d lines, want %d: %s", lines, tt.wantLines, export.body)
+			}
+			if want := `attachment; filename="tags-staging.` + tt.format + `"`; export.header[fiber.HeaderContentDisposition] != want {
+				t.Errorf("Content-Disposition = %q, want %q", export.header[fiber.HeaderContentDisposition], want)
+			}
+
+			target := "production-" + tt.format
+			for _, dryRun := range []bool{true, false} {
+				imported := do(t, app, testRequest{
+					method:      "POST",
+					target:      "/api/v1/tags/import?dry_run=" + map[bool]string{true: "true", false: "false"}[dryRun],
+					tenant:      target,
+					contentType: tt.contentType,
+					body:        string(export.body),
+				})
+				if imported.status != 200 {
+					t.Fatalf("import: status %d: %s", imported.status, imported.body)
+				}
+
+				var res struct {
+					Data struct {
+						DryRun  bool `json:"dry_run"`
+						Created int  `json:"created"`
+					} `json:"data"`
+				}
+				if err := json.Unmarshal(imported.body, &res); err != nil {
+					t.Fatalf("decode import report: %v", err)
+				}
+				if res.Data.DryRun != dryRun || res.Data.Created != 3 {
+					t.Fatalf("import report = %s", imported.body)
+				}
+			}
+
+			list := do(t, app, testRequest{method: "GET", target: "/api/v1/tags", tenant: target})
+			for _, name := range []string{"street", "night", "ทะเล"} {
+				if !strings.Contains(string(list.body), `"name":"`+name+`"`) {
+					t.Errorf("imported tags %s are missing %q", list.body, name)
+				}
+			}
+		})
+	}
+}
+
+func newSQLiteDB(t *testing.T) *sqlstore.DB {
+	t.Helper()
+
+	ctx := context.Background()
+	db, err := sqlstore.Open(ctx, sqlstore.SQLite, filepath.Join(t.TempDir(), "gallery.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	if _, err := db.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	return db
+}
+
+// TestTagsExportSQL streams from database/sql rows, which close when the
+// context they were opened with ends
+func TestTagsExportSQL(t *testing.T) {
+	app := newTestAppWith(t, sqlstore.NewTagsRepository(newSQLiteDB(t)), time.Second)
+	for _, name := range []string{"street", "night", "ทะเล"} {
+		do(t, app, testRequest{method: "POST", target: "/api/v1/tags", tenant: "staging", body: `{"name":"` + name + `"}`})
+	}
+
+	for format, wantLines := range map[string]int{"json": 5, "ndjson": 3, "csv": 4} {
+		export := do(t, app, testRequest{method: "GET", target: "/api/v1/tags/export?format=" + format, tenant: "staging"})
+		if export.status != 200 {
+			t.Fatalf("%s export: status %d: %s", format, export.status, export.body)
+		}
+		if lines := strings.Count(string(export.body), "\n"); lines != wantLines {
+			t.Errorf("%s export has %d lines, want %d: %q", format, lines, wantLines, export.body)
+		}
+	}
+}
+
+// TestTagsRequestTimeout blocks a real repository write behind another
+// writer; the request must end at REQUEST_TIMEOUT, not the 5s busy timeout
+func TestTagsRequestTimeout(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLiteDB(t)
+	app := newTestAppWith(t, sqlstore.NewTagsRepository(db), 100*time.Millisecond)
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Conn: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		t.Fatalf("lock database: %v", err)
+	}
+	defer conn.ExecContext(ctx, "ROLLBACK")
+
+	start := time.Now()
+	res := do(t, app, testRequest{method: "POST", target: "/api/v1/tags", tenant: "acme", body: `{"name":"street"}`})
+	if res.status != fiber.StatusGatewayTimeout || res.problem.Code != "REQUEST_TIMEOUT" {
+		t.Fatalf("status = %d, code = %q, want 504 REQUEST_TIMEOUT: %s", res.status, res.problem.Code, res.body)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("request took %s, want about the 100ms timeout", elapsed)
+	}
+}
+
+// TestTagsImportOutlivesRequestTimeout waits behind another writer for
+// longer than REQUEST_TIMEOUT, which only bounds the other routes
+func TestTagsImportOutlivesRequestTimeout(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLiteDB(t)
+	app := newTestAppWith(t, sqlstore.NewTagsRepository(db), 100*time.Millisecond)
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Conn: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		t.Fatalf("lock database: %v", err)
+	}
+	unlock := time.AfterFunc(300*time.Millisecond, func() { _, _ = conn.ExecContext(ctx, "ROLLBACK") })
+	defer unlock.Stop()
+
+	res := do(t, app, testRequest{method: "POST", target: "/api/v1/tags/import", tenant: "acme", body: `[{"name":"street"}]`})
+	if res.status != 200 {
+		t.Fatalf("import: status %d: %s", res.status, res.body)
+	}
+}
+
+func TestTagsBodyLimit(t *testing.T) {
+	app := newTestApp(t)
+
+	long := strings.Repeat("a", testBodyLimit)
+	res := do(t, app, testRequest{method: "POST", target: "/api/v1/tags", tenant: "acme", body: `{"name":"` + long + `"}`})
+	if res.status != fiber.StatusRequestEntityTooLarge || res.problem.Code != "PAYLOAD_TOO_LARGE" {
+		t.Fatalf("create: status %d code %q, want 413 PAYLOAD_TOO_LARGE: %s", res.status, res.problem.Code, res.body)
+	}
+
+	// Imports are streamed, so BodyLimit does not apply
+	var file strings.Builder
+	for i := range 500 {
+		fmt.Fprintf(&file, "{\"name\":\"tag %d\"}\n", i)
+	}
+	if file.Len() <= testBodyLimit {
+		t.Fatalf("file of %d bytes is within the body limit", file.Len())
+	}
+	res = do(t, app, testRequest{method: "POST", target: "/api/v1/tags/import", tenant: "acme", contentType: "application/x-ndjson", body: file.String()})
+	if res.status != 200 {
+		t.Fatalf("import: status %d: %s", res.status, res.body)
+	}
+	var report struct {
+		Data dto.TagImportReport `json:"data"`
+	}
+	if err := json.Unmarshal(res.body, &report); err != nil {
+		t.Fatalf("decode %s: %v", res.body, err)
+	}
+	if report.Data.Created != 500 {
+		t.Errorf("created %d tags, want 500", report.Data.Created)
+	}
+}
+
+func TestTagsImportErrors(t *testing.T) {
+	app := newTestApp(t)
+
+	tests := []struct {
+		name        string
+		target      string
+		contentType string
+		body        string
+		wantCode    string
+	}{
+		{name: "unknown content type", target: "/api/v1/tags/import", contentType: "text/plain", body: "street", wantCode: "TAG_IMPORT_FORMAT_REQUIRED"},
+		{name: "unknown strategy", target: "/api/v1/tags/import?strategy=replace", body: `[]`, wantCode: "VALIDATION_FAILED"},
+		{name: "malformed file", target: "/api/v1/tags/import?format=csv", contentType: "text/plain", body: "label\nstreet\n", wantCode: "INVALID_REQUEST_BODY"},
+		{name: "bad dry_run", target: "/api/v1/tags/import?dry_run=maybe", body: `[]`, wantCode: "INVALID_QUERY"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := do(t, app, testRequest{method: "POST", target: tt.target, tenant: "acme", contentType: tt.contentType, body: tt.body})
+			if res.status != 400 || res.problem.Code != tt.wantCode {
+				t.Fatalf("status %d code %q, want 400 %q: %s", res.status, res.problem.Code, tt.wantCode, res.body)
+			}
+		})
+	}
+}
+
+func TestTagsListFilters(t *testing.T) {
+	app := newTestApp(t)
+	for _, name := range []string{"Black and White", "blackbird", "street"} {
+		do(t, app, testRequest{method: "POST", target: "/api/v1/tags", tenant: "acme", body: `{"name":"` + name + `"}`})
+	}
+	future := url.QueryEscape(time.Now().Add(time.Hour).Format(time.RFC3339))
+
+	tests := []struct {
+		query    string
+		want     []string
+		wantCode string
+	}{
+		{query: "name=street", want: []string{"street"}},
+		{query: "slug=black-and-white", want: []string{"Black and White"}},
+		{query: "prefix=black", want: []string{"blackbird"}},
+		{query: "prefix=black&slug=blackbird", want: []string{"blackbird"}},
+		{query: "created_from=" + future, want: []string{}},
+		{query: "created_to=" + future, want: []string{"Black and White", "blackbird", "street"}},
+		{query: "updated_from=2024-01-01T00:00:00Z&updated_to=" + future, want: []string{"Black and White", "blackbird", "street"}},
+		{query: "created_from=yesterday", wantCode: "VALIDATION_FAILED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			res := do(t, app, testRequest{method: "GET", target: "/api/v1/tags?" + tt.query, tenant: "acme"})
+			if tt.wantCode != "" {
+				if res.status != 400 || res.problem.Code != tt.wantCode {
+					t.Fatalf("status %d code %q, want 400 %q: %s", res.status, res.problem.Code, tt.wantCode, res.body)
+				}
+				return
+			}
+
+			var list struct {
+				Data []struct {
+					Name string `json:"name"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(res.body, &list); err != nil {
+				t.Fatalf("status %d: decode %s: %v", res.status, res.body, err)
+			}
+			got := []string{}
+			for _, tag := range list.Data {
+				got = append(got, tag.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("tags = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+// Package migrations holds the schema migrations of the gallery database.
+// Append new migrations with the next version; never renumber or edit one
+// that has been released.
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"pre-test-gallery-service/internal/repository"
+	"pre-test-gallery-service/pkg/migrate"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LegacyTenant receives the tags created before workspaces existed when no
+// DEFAULT_TENANT is configured
+const LegacyTenant = "default"
+
+const (
+	tagsNameIndex    = "tenant_id_1_name_1"
+	tagsCreatedIndex = "tenant_id_1_created_at_-1"
+)
+
+// All returns the migrations in version order; defaultTenant owns the tags
+// that have no tenant yet
+func All(defaultTenant string) []migrate.Migration {
+	if defaultTenant == "" {
+		defaultTenant = LegacyTenant
+	}
+
+	return []migrate.Migration{
+		{
+			Version:     1,
+			Description: "backfill tenant_id of tags",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection("tags").UpdateMany(ctx,
+					bson.M{"tenant_id": bson.M{"$exists": false}},
+					bson.M{"$set": bson.M{"tenant_id": defaultTenant}},
+				)
+				return err
+			},
+			// Backfilled tags cannot be told apart from tags created in the
+			// default tenant afterwards
+			Down: nil,
+		},
+		{
+			Version:     2,
+			Description: "unique tag name per tenant regardless of case",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				if err := checkDuplicateTagNames(ctx, db); err != nil {
+					return err
+				}
+				return createIndex(ctx, db.Collection("tags"), mongo.IndexModel{
+					Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "name", Value: 1}},
+					Options: options.Index().SetName(tagsNameIndex).SetUnique(true).SetCollation(repository.TagNameCollation),
+				})
+			},
+			Down: dropIndex("tags", tagsNameIndex),
+		},
+		{
+			Version:     3,
+			Description: "index tags by tenant and creation time",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				return createIndex(ctx, db.Collection("tags"), mongo.IndexModel{
+					Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "created_at", Value: -1}},
+					Options: options.Index().SetName(tagsCreatedIndex),
+				})
+			},
+			Down: dropIndex("tags", tagsCreatedIndex),
+		},
+	}
+}
+
+// checkDuplicateTagNames reports the names that would make the unique index
+// build fail, so they can be merged by hand first; names are grouped with
+// the collation of the index
+func checkDuplicateTagNames(ctx context.Context, db *mongo.Database) error {
+	cursor, err := db.Collection("tags").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"tenant_id": "$tenant_id", "name": "$name"},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+		{{Key: "$limit", Value: 10}},
+	}, options.Aggregate().SetCollation(repository.TagNameCollation))
+	if err != nil {
+		return err
+	}
+
+	var duplicates []struct {
+		ID struct {
+			TenantID string `bson:"tenant_id"`
+			Name     string `bson:"name"`
+		} `bson:"_id"`
+		Count int `bson:"count"`
+	}
+	if err := cursor.All(ctx, &duplicates); err != nil {
+		return err
+	}
+	if len(duplicates) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(duplicates))
+	for _, d := range duplicates {
+		names = append(names, fmt.Sprintf("%s/%s (%d)", d.ID.TenantID, d.ID.Name, d.Count))
+	}
+	return fmt.Errorf("duplicate tag names must be merged first: %s", strings.Join(names, ", "))
+}
+
+func createIndex(ctx context.Context, collection *mongo.Collection, model mongo.IndexModel) error {
+	_, err := collection.Indexes().CreateOne(ctx, model)
+	return err
+}
+
+// dropIndex ignores an index that is already gone so Down can be retried
+func dropIndex(collection, name string) migrate.Func {
+	return func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection(collection).Indexes().DropOne(ctx, name)
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && cmdErr.Name == "IndexNotFound" {
+			return nil
+		}
+		return err
+	}
+}
@@ -0,0 +1,14 @@
+package migrations
+
+import "testing"
+
+func TestVersionsAreSequential(t *testing.T) {
+	for i, m := range All("") {
+		if m.Version != i+1 {
+			t.Fatalf("migration %q has version %d, want %d", m.Description, m.Version, i+1)
+		}
+		if m.Up == nil {
+			t.Fatalf("migration %d has no Up", m.Version)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 type Tags struct {
 	ID        primitive.ObjectID `json:"_id" bson:"_id"`
+	TenantID  string             `json:"tenant_id" bson:"tenant_id"`
 	Name      string             `json:"name" bson:"name"`
 	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrNotFound is returned by writes that match no record
+	ErrNotFound = errors.New("record not found")
+	// ErrDuplicateKey is wrapped by backends whose unique constraints are
+	// not MongoDB indexes
+	ErrDuplicateKey = errors.New("duplicate key")
+)
+
+// IsDuplicateKey reports whether a write broke a unique index, whatever the
+// backend
+func IsDuplicateKey(err error) bool {
+	return errors.Is(err, ErrDuplicateKey) || mongo.IsDuplicateKeyError(err)
+}
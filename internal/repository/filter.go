@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"pre-test-gallery-service/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TagsFilter selects tags of the context's tenant; zero fields do not
+// filter, so the zero value matches every tag. Backends translate it to
+// their own query language and Matches is the reference semantics
+type TagsFilter struct {
+	ID primitive.ObjectID
+	// Name ignores case, as tag names are unique regardless of case
+	Name string
+	// Slug matches names with the same Slugify, e.g. "black-and-white"
+	// matches "Black and White"
+	Slug string
+	// NamePrefix is case sensitive
+	NamePrefix string
+	CreatedAt  TimeRange
+	UpdatedAt  TimeRange
+}
+
+// TagByID matches the tag with id
+func TagByID(id primitive.ObjectID) TagsFilter {
+	return TagsFilter{ID: id}
+}
+
+// TagByName matches the tag named name in any case
+func TagByName(name string) TagsFilter {
+	return TagsFilter{Name: name}
+}
+
+// TagBySlug matches the tags whose name has slug
+func TagBySlug(slug string) TagsFilter {
+	return TagsFilter{Slug: slug}
+}
+
+// Matches reports whether tag passes every set field
+func (f TagsFilter) Matches(tag model.Tags) bool {
+	switch {
+	case !f.ID.IsZero() && tag.ID != f.ID:
+		return false
+	case f.Name != "" && !strings.EqualFold(tag.Name, f.Name):
+		return false
+	case f.Slug != "" && Slugify(tag.Name) != Slugify(f.Slug):
+		return false
+	case f.NamePrefix != "" && !strings.HasPrefix(tag.Name, f.NamePrefix):
+		return false
+	}
+	return f.CreatedAt.Contains(tag.CreatedAt) && f.UpdatedAt.Contains(tag.UpdatedAt)
+}
+
+// TimeRange is the half-open interval [From, To); a zero bound is open
+type TimeRange struct {
+	From time.Time
+	To   time.Time
+}
+
+func (r TimeRange) IsZero() bool {
+	return r.From.IsZero() && r.To.IsZero()
+}
+
+func (r TimeRange) Contains(t time.Time) bool {
+	if !r.From.IsZero() && t.Before(r.From) {
+		return false
+	}
+	if !r.To.IsZero() && !t.Before(r.To) {
+		return false
+	}
+	return true
+}
+
+// Slugify lower-cases a tag name and joins its words with hyphens. Tag
+// names separate words with spaces, hyphens and underscores
+func Slugify(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return r == ' ' || r == '_' || r == '-'
+	})
+	return strings.Join(words, "-")
+}
+
+// FoldName maps every letter to one representative of its case, so names
+// equal under strings.EqualFold fold to the same string, in any script
+func FoldName(name string) string {
+	return strings.Map(func(r rune) rune {
+		folded := r
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			folded = min(folded, f)
+		}
+		return folded
+	}, name)
+}
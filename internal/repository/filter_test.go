@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"pre-test-gallery-service/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"street":             "street",
+		"Black and White":    "black-and-white",
+		"black__and--white ": "black-and-white",
+		"ถนน คนเดิน":         "ถนน-คนเดิน",
+		"":                   "",
+	}
+	for name, want := range tests {
+		if got := Slugify(name); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestFoldName(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{a: "Street", b: "sTREET", same: true},
+		{a: "Éclair", b: "éCLAIR", same: true},
+		{a: "Éclair", b: "eclair", same: false},
+		{a: "ΣΟΦΊΑ", b: "σοφία", same: true},
+		// The Kelvin sign folds with k, as in strings.EqualFold
+		{a: "\u212Aelvin", b: "kelvin", same: true},
+		{a: "ถนน", b: "ถนน", same: true},
+	}
+	for _, tt := range tests {
+		if got := FoldName(tt.a) == FoldName(tt.b); got != tt.same {
+			t.Errorf("FoldName(%q) == FoldName(%q) is %v, want %v", tt.a, tt.b, got, tt.same)
+		}
+		if got := strings.EqualFold(tt.a, tt.b); got != tt.same {
+			t.Errorf("strings.EqualFold(%q, %q) = %v, disagrees with FoldName", tt.a, tt.b, got)
+		}
+	}
+}
+
+func TestTagsQuery(t *testing.T) {
+	id := primitive.NewObjectID()
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	tests := []struct {
+		name   string
+		filter TagsFilter
+		want   bson.M
+	}{
+		{name: "zero", filter: TagsFilter{}, want: bson.M{}},
+		{name: "id", filter: TagByID(id), want: bson.M{"_id": id}},
+		{name: "name", filter: TagByName("street"), want: bson.M{"name": "street"}},
+		{name: "prefix", filter: TagsFilter{NamePrefix: "a.b"}, want: bson.M{"name": primitive.Regex{Pattern: `^a\.b`}}},
+		{name: "slug", filter: TagBySlug("Black-and white"), want: bson.M{"name": primitive.Regex{Pattern: "^[ _-]*black[ _-]+and[ _-]+white[ _-]*$", Options: "i"}}},
+		{
+			name:   "name and prefix",
+			filter: TagsFilter{Name: "street", NamePrefix: "st"},
+			want:   bson.M{"$and": bson.A{bson.M{"name": "street"}, bson.M{"name": primitive.Regex{Pattern: "^st"}}}},
+		},
+		{
+			name:   "ranges",
+			filter: TagsFilter{CreatedAt: TimeRange{From: from, To: to}, UpdatedAt: TimeRange{To: to}},
+			want:   bson.M{"created_at": bson.M{"$gte": from, "$lt": to}, "updated_at": bson.M{"$lt": to}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tagsQuery(tt.filter); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("tagsQuery(%+v) = %v, want %v", tt.filter, got, tt.want)
+			}
+		})
+	}
+}
+
+// The MongoDB regexes must agree with Matches on the names tags can have
+func TestTagsQueryRegexAgreesWithMatches(t *testing.T) {
+	names := []string{"Black and White", "black_and-white", "black-and-white-", " _black and white", "blackandwhite", "black and white and", "Black", "ถนน คนเดิน"}
+	filters := []TagsFilter{
+		TagBySlug("black-and-white"),
+		TagBySlug("BLACK"),
+		TagBySlug("ถนน-คนเดิน"),
+		{NamePrefix: "black"},
+		{NamePrefix: "Black and"},
+	}
+
+	for _, filter := range filters {
+		regex, ok := tagsQuery(filter)["name"].(primitive.Regex)
+		if !ok {
+			t.Fatalf("tagsQuery(%+v) has no name regex", filter)
+		}
+		pattern := regex.Pattern
+		if regex.Options == "i" {
+			pattern = "(?i)" + pattern
+		}
+		re := regexp.MustCompile(pattern)
+
+		for _, name := range names {
+			if got, want := re.MatchString(name), filter.Matches(model.Tags{Name: name}); got != want {
+				t.Errorf("%+v on %q: regex %t, Matches %t", filter, name, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,202 @@
+// Package memory implements the repository interfaces in process memory
+// with the semantics of the MongoDB implementations, for tests and offline
+// development. Data is lost when the process exits.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"pre-test-gallery-service/internal/model"
+	"pre-test-gallery-service/internal/repository"
+	"pre-test-gallery-service/pkg/tenant"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type tagsRepository struct {
+	mu sync.RWMutex
+	// tags is kept in insertion order, like a collection without a sort
+	tags []model.Tags
+}
+
+// NewTagsRepository returns an empty repository that enforces the unique
+// (tenant_id, name) index of the tags collection
+func NewTagsRepository() repository.TagsRepository {
+	return &tagsRepository{}
+}
+
+func (r *tagsRepository) FindAll(ctx context.Context, filter repository.TagsFilter) ([]model.Tags, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.find(ctx, filter)
+}
+
+func (r *tagsRepository) FindOne(ctx context.Context, filter repository.TagsFilter) (*model.Tags, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tags, err := r.find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	return &tags[0], nil
+}
+
+func (r *tagsRepository) Stream(ctx context.Context, filter repository.TagsFilter) (repository.TagsCursor, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tags, err := r.find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return &tagsCursor{tags: tags, pos: -1}, nil
+}
+
+func (r *tagsRepository) Create(ctx context.Context, tags *model.Tags) error {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.nameTaken(tenantID, tags.Name, primitive.NilObjectID) {
+		return duplicateKeyError(tenantID, tags.Name)
+	}
+
+	now := time.Now()
+	tags.ID = primitive.NewObjectID()
+	tags.TenantID = tenantID
+	if tags.CreatedAt.IsZero() {
+		tags.CreatedAt = now
+	}
+	tags.UpdatedAt = now
+
+	r.tags = append(r.tags, *tags)
+	return nil
+}
+
+func (r *tagsRepository) Update(ctx context.Context, tags *model.Tags) error {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := r.index(tenantID, tags.ID)
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	if r.nameTaken(tenantID, tags.Name, tags.ID) {
+		return duplicateKeyError(tenantID, tags.Name)
+	}
+
+	tags.UpdatedAt = time.Now()
+	stored := &r.tags[i]
+	stored.Name = tags.Name
+	stored.CreatedAt = tags.CreatedAt
+	stored.UpdatedAt = tags.UpdatedAt
+	return nil
+}
+
+// Delete succeeds when nothing matches, like DeleteOne
+func (r *tagsRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if i := r.index(tenantID, id); i >= 0 {
+		r.tags = append(r.tags[:i], r.tags[i+1:]...)
+	}
+	return nil
+}
+
+// find returns copies of the tags of the context's tenant matching filter
+func (r *tagsRepository) find(ctx context.Context, filter repository.TagsFilter) ([]model.Tags, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]model.Tags, 0)
+	for _, tag := range r.tags {
+		if tag.TenantID == tenantID && filter.Matches(tag) {
+			matches = append(matches, tag)
+		}
+	}
+	return matches, nil
+}
+
+func (r *tagsRepository) index(tenantID string, id primitive.ObjectID) int {
+	for i, tag := range r.tags {
+		if tag.TenantID == tenantID && tag.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// nameTaken ignores case like the collation of the MongoDB name index
+func (r *tagsRepository) nameTaken(tenantID, name string, except primitive.ObjectID) bool {
+	for _, tag := range r.tags {
+		if tag.TenantID == tenantID && strings.EqualFold(tag.Name, name) && tag.ID != except {
+			return true
+		}
+	}
+	return false
+}
+
+func duplicateKeyError(tenantID, name string) error {
+	return fmt.Errorf("%w: tag %q already exists in tenant %q", repository.ErrDuplicateKey, name, tenantID)
+}
+
+// tagsCursor iterates over a snapshot taken when the stream was opened
+type tagsCursor struct {
+	tags []model.Tags
+	pos  int
+	err  error
+}
+
+func (c *tagsCursor) Next(ctx context.Context) bool {
+	if err := ctx.Err(); err != nil {
+		c.err = err
+		return false
+	}
+	c.pos++
+	return c.pos < len(c.tags)
+}
+
+func (c *tagsCursor) Decode(tag *model.Tags) error {
+	if c.pos < 0 || c.pos >= len(c.tags) {
+		return repository.ErrNotFound
+	}
+	*tag = c.tags[c.pos]
+	return nil
+}
+
+func (c *tagsCursor) Err() error {
+	return c.err
+}
+
+func (c *tagsCursor) Close(ctx context.Context) error {
+	c.tags = nil
+	return nil
+}
@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"pre-test-gallery-service/internal/repository"
+	"pre-test-gallery-service/internal/repository/memory"
+	"pre-test-gallery-service/internal/repository/repotest"
+)
+
+func TestTagsRepositoryContract(t *testing.T) {
+	repotest.TagsRepository(t, func(t *testing.T) repository.TagsRepository {
+		return memory.NewTagsRepository()
+	})
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"pre-test-gallery-service/pkg/logger"
+	"pre-test-gallery-service/pkg/metrics"
+	"pre-test-gallery-service/pkg/tracing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startOperation opens a span for a repository call. The returned function
+// ends it, observes the latency and logs the call with the request logger,
+// so it carries the request ID of the caller.
+func startOperation(ctx context.Context, collection, op string) (context.Context, func(error)) {
+	start := time.Now()
+	ctx, span := tracing.Tracer().Start(ctx, collection+"Repository."+op, trace.WithAttributes(
+		attribute.String("db.collection.name", collection),
+		attribute.String("db.operation.name", op),
+	))
+
+	return ctx, func(err error) {
+		elapsed := time.Since(start)
+		tracing.End(span, err)
+		metrics.MongoOperationDuration.WithLabelValues(collection, op, metrics.Outcome(err)).Observe(elapsed.Seconds())
+
+		attrs := []any{
+			slog.String("collection", collection),
+			slog.String("op", op),
+			slog.Float64("duration_ms", float64(elapsed.Microseconds())/1000),
+		}
+
+		if err != nil {
+			logger.FromContext(ctx).Error("MongoDB operation failed", append(attrs, slog.Any("error", err))...)
+			return
+		}
+		logger.FromContext(ctx).Debug("MongoDB operation", attrs...)
+	}
+}
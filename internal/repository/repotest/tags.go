@@ -0,0 +1,433 @@
+// Package repotest holds the contract every repository implementation must
+// satisfy. Each backend runs the same suite from its own tests, so the
+// in-memory, MongoDB and future backends cannot drift apart.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"pre-test-gallery-service/internal/model"
+	"pre-test-gallery-service/internal/repository"
+	"pre-test-gallery-service/pkg/tenant"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NewTagsRepository returns an empty repository for one subtest
+type NewTagsRepository func(t *testing.T) repository.TagsRepository
+
+// TagsRepository runs the TagsRepository contract against newRepo
+func TagsRepository(t *testing.T, newRepo NewTagsRepository) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo repository.TagsRepository)
+	}{
+		{name: "CreateAndFind", fn: testCreateAndFind},
+		{name: "CreateKeepsCreatedAt", fn: testCreateKeepsCreatedAt},
+		{name: "FindOneMiss", fn: testFindOneMiss},
+		{name: "FindAllEmpty", fn: testFindAllEmpty},
+		{name: "UniqueNamePerTenant", fn: testUniqueNamePerTenant},
+		{name: "Update", fn: testUpdate},
+		{name: "UpdateMissing", fn: testUpdateMissing},
+		{name: "Delete", fn: testDelete},
+		{name: "DeleteMissing", fn: testDeleteMissing},
+		{name: "StreamOrderedByName", fn: testStreamOrderedByName},
+		{name: "Filters", fn: testFilters},
+		{name: "TenantIsolation", fn: testTenantIsolation},
+		{name: "RequiresTenant", fn: testRequiresTenant},
+		{name: "ConcurrentCreates", fn: testConcurrentCreates},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newRepo(t))
+		})
+	}
+}
+
+func tenantContext(id string) context.Context {
+	return tenant.WithTenant(context.Background(), id)
+}
+
+func create(t *testing.T, ctx context.Context, repo repository.TagsRepository, name string) *model.Tags {
+	t.Helper()
+
+	tag := &model.Tags{Name: name}
+	if err := repo.Create(ctx, tag); err != nil {
+		t.Fatalf("Create(%q): %v", name, err)
+	}
+	return tag
+}
+
+func names(tags []model.Tags) []string {
+	out := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		out = append(out, tag.Name)
+	}
+	return out
+}
+
+func testCreateAndFind(t *testing.T, repo repository.TagsRepository) {
+	ctx := tenantContext("acme")
+	created := create(t, ctx, repo, "street")
+
+	if created.ID.IsZero() || created.TenantID != "acme" || created.CreatedAt.IsZero() || created.UpdatedAt.IsZero() {
+		t.Fatalf("Create did not fill ID, tenant and timestamps: %+v", created)
+	}
+
+	byName, err := repo.FindOne(ctx, repository.TagByName("street"))
+	if err != nil || byName == nil {
+		t.Fatalf("FindOne by name = %v, %v", byName, err)
+	}
+	byID, err := repo.FindOne(ctx, repository.TagByID(created.ID))
+	if err != nil || byID == nil {
+		t.Fatalf("FindOne by ID = %v, %v", byID, err)
+	}
+	if byName.ID != created.ID || byID.Name != "street" || byID.TenantID != "acme" {
+		t.Fatalf("found %+v and %+v, want %+v", byName, byID, created)
+	}
+	// Stores keep at least millisecond precision
+	if d := byID.CreatedAt.Sub(created.CreatedAt); d < -time.Millisecond || d > time.Millisecond {
+		t.Fatalf("CreatedAt = %v, want %v", byID.CreatedAt, created.CreatedAt)
+	}
+
+	all, err := repo.FindAll(ctx, repository.TagsFilter{})
+	if err != nil || len(all) != 1 || all[0].ID != created.ID {
+		t.Fatalf("FindAll = %v, %v", all, err)
+	}
+}
+
+func testCreateKeepsCreatedAt(t *testing.T, repo repository.TagsRepository) {
+	ctx := tenantContext("acme")
+	createdAt := time.Date(2020, 5, 6, 7, 8, 9, 0, time.UTC)
+
+	if err := repo.Create(ctx, &model.Tags{Name: "imported", CreatedAt: createdAt}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	found, err := repo.FindOne(ctx, repository.TagByName("imported"))
+	if err != nil || found == nil {
+		t.Fatalf("FindOne = %v, %v", found, err)
+	}
+	if !found.CreatedAt.Equal(createdAt) {
+		t.Fatalf("CreatedAt = %v, want %v", found.CreatedAt, createdAt)
+	}
+}
+
+func testFindOneMiss(t *testing.T, repo repository.TagsRepository) {
+	ctx := tenantContext("acme")
+	create(t, ctx, repo, "street")
+
+	for _, query := range []repository.TagsFilter{repository.TagByName("night"), repository.TagByID(primitive.NewObjectID())} {
+		tag, err := repo.FindOne(ctx, query)
+		if tag != nil || err != nil {
+			t.Fatalf("FindOne(%v) = %v, %v, want nil, nil", query, tag, err)
+		}
+	}
+}
+
+func testFindAllEmpty(t *testing.T, repo repository.TagsRepository) {
+	tags, err := repo.FindAll(tenantContext("acme"), repository.TagsFilter{})
+	if err != nil || len(tags) != 0 {
+		t.Fatalf("FindAll = %v, %v, want no tags", tags, err)
+	}
+}
+
+func testUniqueNamePerTenant(t *testing.T, repo repository.TagsRepository) {
+	ctx := tenantContext("acme")
+	create(t, ctx, repo, "street")
+
+	err := repo.Create(ctx, &model.Tags{Name: "street"})
+	if !repository.IsDuplicateKey(err) {
+		t.Fatalf("second Create error = %v, want a duplicate key error", err)
+	}
+
+	create(t, tenantContext("other"), repo, "street")
+
+	// Names are unique regardless of case, and found in any case
+	err = repo.Create(ctx, &model.Tags{Name: "Street"})
+	if !repository.IsDuplicateKey(err) {
+		t.Fatalf("Create in another case error = %v, want a duplicate key error", err)
+	}
+	found, err := repo.FindOne(ctx, repository.TagByName("STREET"))
+	if err != nil || found == nil || found.Name != "street" {
+		t.Fatalf("FindOne(STREET) = %+v, %v, want street", found, err)
+	}
+
+	// Beyond ASCII too, while accents still tell names apart
+	create(t, ctx, repo, "Éclair")
+	err = repo.Create(ctx, &model.Tags{Name: "éclair"})
+	if !repository.IsDuplicateKey(err) {
+		t.Fatalf("Create(éclair) error = %v, want a duplicate key error", err)
+	}
+	found, err = repo.FindOne(ctx, repository.TagByName("ÉCLAIR"))
+	if err != nil || found == nil || found.Name != "Éclair" {
+		t.Fatalf("FindOne(ÉCLAIR) = %+v, %v, want Éclair", found, err)
+	}
+	create(t, ctx, repo, "eclair")
+
+	night := create(t, ctx, repo, "night")
+	night.Name = "street"
+	if err := repo.Update(ctx, night); !repository.IsDuplicateKey(err) {
+		t.Fatalf("Update to a taken name error = %v, want a duplicate key error", err)
+	}
+}
+
+func testUpdate(t *testing.T, repo repository.TagsRepository) {
+	ctx := tenantContext("acme")
+	tag := create(t, ctx, repo, "street")
+	before := tag.UpdatedAt
+
+	time.Sleep(2 * time.Millisecond)
+	createdAt := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	tag.Name = "street photography"
+	tag.CreatedAt = createdAt
+	if err := repo.Update(ctx, tag); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	found, err := repo.FindOne(ctx, repository.TagByID(tag.ID))
+	if err != nil || found == nil {
+		t.Fatalf("FindOne = %v, %v", found, err)
+	}
+	if found.Name != "street photography" || !found.CreatedAt.Equal(createdAt) || !found.UpdatedAt.After(before) {
+		t.Fatalf("after Update found %+v", found)
+	}
+}
+
+func testUpdateMissing(t *testing.T, repo repository.TagsRepository) {
+	ctx := tenantContext("acme")
+	tag := create(t, ctx, repo, "street")
+
+	missing := &model.Tags{ID: primitive.NewObjectID(), Name: "night"}
+	if err := repo.Update(ctx, missing); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Update of a missing tag error = %v, want %v", err, repository.ErrNotFound)
+	}
+
+	tag.Name = "moved"
+	if err := repo.Update(tenantContext("other"), tag); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Update from another tenant error = %v, want %v", err, repository.ErrNotFound)
+	}
+}
+
+func testDelete(t *testing.T, repo repository.TagsRepository) {
+	ctx := tenantContext("acme")
+	street := create(t, ctx, repo, "street")
+	create(t, ctx, repo, "night")
+
+	if err := repo.Delete(ctx, street.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if tag, err := repo.FindOne(ctx, repository.TagByID(street.ID)); tag != nil || err != nil {
+		t.Fatalf("FindOne after Delete = %v, %v", tag, err)
+	}
+
+	all, err := repo.FindAll(ctx, repository.TagsFilter{})
+	if err != nil || len(all) != 1 || all[0].Name != "night" {
+		t.Fatalf("FindAll after Delete = %v, %v", names(all), err)
+	}
+
+	// The name can be used again
+	create(t, ctx, repo, "street")
+}
+
+func testDeleteMissing(t *testing.T, repo repository.TagsRepository) {
+	ctx := tenantContext("acme")
+	tag := create(t, ctx, repo, "street")
+
+	if err := repo.Delete(ctx, primitive.NewObjectID()); err != nil {
+		t.Fatalf("Delete of a missing tag: %v", err)
+	}
+	if err := repo.Delete(tenantContext("other"), tag.ID); err != nil {
+		t.Fatalf("Delete from another tenant: %v", err)
+	}
+	if found, err := repo.FindOne(ctx, repository.TagByID(tag.ID)); found == nil || err != nil {
+		t.Fatalf("Delete from another tenant removed the tag: %v, %v", found, err)
+	}
+}
+
+func testStreamOrderedByName(t *testing.T, repo repository.TagsRepository) {
+	ctx := tenantContext("acme")
+	want := []string{"architecture", "landscape", "night", "street", "travel"}
+	for _, name := range []string{"street", "night", "travel", "architecture", "landscape"} {
+		create(t, ctx, repo, name)
+	}
+	create(t, tenantContext("other"), repo, "food")
+
+	// The cursor is read after the context it was opened with has ended, as
+	// a streamed response is; only the context passed to Next may stop it
+	openCtx, cancel := context.WithCancel(ctx)
+	cursor, err := repo.Stream(openCtx, repository.TagsFilter{})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	defer cursor.Close(ctx)
+	cancel()
+	// database/sql closes rows from another goroutine once their context ends
+	time.Sleep(20 * time.Millisecond)
+
+	var got []string
+	for cursor.Next(ctx) {
+		var tag model.Tags
+		if err := cursor.Decode(&tag); err != nil {
+			t.Fatalf("Decode: %v", err)
+		}
+		got = append(got, tag.Name)
+	}
+	if err := cursor.Err(); err != nil {
+		t.Fatalf("cursor: %v", err)
+	}
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("Stream order = %v, want %v", got, want)
+	}
+
+	filtered, err := repo.Stream(ctx, repository.TagByName("night"))
+	if err != nil {
+		t.Fatalf("Stream with query: %v", err)
+	}
+	defer filtered.Close(ctx)
+	count := 0
+	for filtered.Next(ctx) {
+		count++
+	}
+	if count != 1 {
+		t.Fatalf("Stream with query returned %d tags, want 1", count)
+	}
+}
+
+func testFilters(t *testing.T, repo repository.TagsRepository) {
+	ctx := tenantContext("acme")
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	for i, name := range []string{"Black and White", "black_and-white", "blackbird", "Black", "street", "ถนน คนเดิน", "a.b", "-night-"} {
+		if err := repo.Create(ctx, &model.Tags{Name: name, CreatedAt: day(i + 1)}); err != nil {
+			t.Fatalf("Create(%q): %v", name, err)
+		}
+	}
+	create(t, tenantContext("other"), repo, "black-and-white")
+
+	tests := []struct {
+		name   string
+		filter repository.TagsFilter
+		want   []string
+	}{
+		{name: "zero", filter: repository.TagsFilter{}, want: []string{"-night-", "Black", "Black and White", "a.b", "black_and-white", "blackbird", "street", "ถนน คนเดิน"}},
+		{name: "name ignores case", filter: repository.TagByName("black"), want: []string{"Black"}},
+		{name: "name is whole", filter: repository.TagByName("blac"), want: []string{}},
+		{name: "slug", filter: repository.TagBySlug("black-and-white"), want: []string{"Black and White", "black_and-white"}},
+		{name: "slug is normalised", filter: repository.TagBySlug("Black  And_White"), want: []string{"Black and White", "black_and-white"}},
+		{name: "slug matches whole words", filter: repository.TagBySlug("black"), want: []string{"Black"}},
+		{name: "slug ignores outer separators", filter: repository.TagBySlug("night"), want: []string{"-night-"}},
+		{name: "unicode slug", filter: repository.TagBySlug("ถนน-คนเดิน"), want: []string{"ถนน คนเดิน"}},
+		{name: "prefix", filter: repository.TagsFilter{NamePrefix: "black"}, want: []string{"black_and-white", "blackbird"}},
+		{name: "prefix is literal", filter: repository.TagsFilter{NamePrefix: "a."}, want: []string{"a.b"}},
+		{name: "prefix is not a pattern", filter: repository.TagsFilter{NamePrefix: ".*"}, want: []string{}},
+		{name: "created from", filter: repository.TagsFilter{CreatedAt: repository.TimeRange{From: day(6)}}, want: []string{"-night-", "a.b", "ถนน คนเดิน"}},
+		{name: "created to is exclusive", filter: repository.TagsFilter{CreatedAt: repository.TimeRange{To: day(2)}}, want: []string{"Black and White"}},
+		{name: "created between", filter: repository.TagsFilter{CreatedAt: repository.TimeRange{From: day(2), To: day(4)}}, want: []string{"black_and-white", "blackbird"}},
+		{name: "updated in the future", filter: repository.TagsFilter{UpdatedAt: repository.TimeRange{From: time.Now().Add(time.Hour)}}, want: []string{}},
+		{name: "combined", filter: repository.TagsFilter{NamePrefix: "Black", Slug: "black", CreatedAt: repository.TimeRange{From: day(4)}}, want: []string{"Black"}},
+		{name: "name and prefix", filter: repository.TagsFilter{Name: "street", NamePrefix: "str"}, want: []string{"street"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags, err := repo.FindAll(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("FindAll: %v", err)
+			}
+			got := names(tags)
+			sort.Strings(got)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("FindAll(%+v) = %q, want %q", tt.filter, got, tt.want)
+			}
+		})
+	}
+}
+
+func testTenantIsolation(t *testing.T, repo repository.TagsRepository) {
+	acme, other := tenantContext("acme"), tenantContext("other")
+	tag := create(t, acme, repo, "street")
+	create(t, other, repo, "night")
+
+	if found, err := repo.FindOne(other, repository.TagByID(tag.ID)); found != nil || err != nil {
+		t.Fatalf("FindOne from another tenant = %v, %v", found, err)
+	}
+
+	all, err := repo.FindAll(other, repository.TagsFilter{})
+	if err != nil || fmt.Sprint(names(all)) != "[night]" {
+		t.Fatalf("FindAll(other) = %v, %v", names(all), err)
+	}
+}
+
+func testRequiresTenant(t *testing.T, repo repository.TagsRepository) {
+	ctx := context.Background()
+
+	if _, err := repo.FindAll(ctx, repository.TagsFilter{}); !errors.Is(err, tenant.ErrMissingTenant) {
+		t.Errorf("FindAll error = %v", err)
+	}
+	if _, err := repo.FindOne(ctx, repository.TagByName("street")); !errors.Is(err, tenant.ErrMissingTenant) {
+		t.Errorf("FindOne error = %v", err)
+	}
+	if _, err := repo.Stream(ctx, repository.TagsFilter{}); !errors.Is(err, tenant.ErrMissingTenant) {
+		t.Errorf("Stream error = %v", err)
+	}
+	if err := repo.Create(ctx, &model.Tags{Name: "street"}); !errors.Is(err, tenant.ErrMissingTenant) {
+		t.Errorf("Create error = %v", err)
+	}
+	if err := repo.Update(ctx, &model.Tags{ID: primitive.NewObjectID(), Name: "street"}); !errors.Is(err, tenant.ErrMissingTenant) {
+		t.Errorf("Update error = %v", err)
+	}
+	if err := repo.Delete(ctx, primitive.NewObjectID()); !errors.Is(err, tenant.ErrMissingTenant) {
+		t.Errorf("Delete error = %v", err)
+	}
+}
+
+func testConcurrentCreates(t *testing.T, repo repository.TagsRepository) {
+	const writers = 16
+	ctx := tenantContext("acme")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(2)
+		// Everyone races for the same name...
+		go func() {
+			defer wg.Done()
+			errs <- repo.Create(ctx, &model.Tags{Name: "contested"})
+		}()
+		// ...and creates a name of their own
+		go func(i int) {
+			defer wg.Done()
+			if err := repo.Create(ctx, &model.Tags{Name: fmt.Sprintf("tag %d", i)}); err != nil {
+				t.Errorf("Create(tag %d): %v", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	created := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case !repository.IsDuplicateKey(err):
+			t.Errorf("Create(contested): %v", err)
+		}
+	}
+	if created != 1 {
+		t.Fatalf("%d concurrent creates of the same name succeeded, want 1", created)
+	}
+
+	all, err := repo.FindAll(ctx, repository.TagsFilter{})
+	if err != nil || len(all) != writers+1 {
+		t.Fatalf("FindAll after concurrent creates = %d tags, %v, want %d", len(all), err, writers+1)
+	}
+}
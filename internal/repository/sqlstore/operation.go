@@ -0,0 +1,43 @@
+package sqlstore
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"pre-test-gallery-service/pkg/logger"
+	"pre-test-gallery-service/pkg/metrics"
+	"pre-test-gallery-service/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startOperation is the SQL counterpart of the MongoDB repository helper: a
+// span, a latency observation and a log line with the request ID
+func startOperation(ctx context.Context, dialect Dialect, table, op string) (context.Context, func(error)) {
+	start := time.Now()
+	ctx, span := tracing.Tracer().Start(ctx, table+"Repository."+op, trace.WithAttributes(
+		attribute.String("db.system", string(dialect)),
+		attribute.String("db.collection.name", table),
+		attribute.String("db.operation.name", op),
+	))
+
+	return ctx, func(err error) {
+		elapsed := time.Since(start)
+		tracing.End(span, err)
+		metrics.SQLOperationDuration.WithLabelValues(table, op, metrics.Outcome(err)).Observe(elapsed.Seconds())
+
+		attrs := []any{
+			slog.String("table", table),
+			slog.String("op", op),
+			slog.Float64("duration_ms", float64(elapsed.Microseconds())/1000),
+		}
+
+		if err != nil {
+			logger.FromContext(ctx).Error("SQL operation failed", append(attrs, slog.Any("error", err))...)
+			return
+		}
+		logger.FromContext(ctx).Debug("SQL operation", attrs...)
+	}
+}
@@ -0,0 +1,156 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// migration is one schema version; statements run in one transaction, which
+// both dialects support for DDL
+type migration struct {
+	version     int
+	description string
+	statements  func(d Dialect) []string
+}
+
+// migrations are the schema versions in order; never edit an applied one,
+// append a new version instead
+var migrations = []migration{
+	{
+		version:     1,
+		description: "create tags",
+		statements: func(d Dialect) []string {
+			return []string{
+				`CREATE TABLE tags (
+					id TEXT PRIMARY KEY,
+					tenant_id TEXT NOT NULL,
+					name TEXT NOT NULL,
+					name_folded TEXT NOT NULL,
+					slug TEXT NOT NULL,
+					created_at ` + d.timestampType() + ` NOT NULL,
+					updated_at ` + d.timestampType() + ` NOT NULL,
+					CONSTRAINT tags_tenant_id_name_folded_key UNIQUE (tenant_id, name_folded)
+				)`,
+				`CREATE INDEX tags_tenant_id_slug_idx ON tags (tenant_id, slug)`,
+				`CREATE INDEX tags_tenant_id_created_at_idx ON tags (tenant_id, created_at)`,
+			}
+		},
+	},
+}
+
+// MigrationStatus is a schema version and when it was applied
+type MigrationStatus struct {
+	Version     int
+	Description string
+	AppliedAt   *time.Time
+}
+
+// Migrate applies the pending schema versions. Postgres replicas starting
+// together wait on an advisory lock; SQLite serializes on its write lock
+func (db *DB) Migrate(ctx context.Context) (applied int, err error) {
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if db.Dialect == Postgres {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('gallery schema_migrations'))`); err != nil {
+			return 0, err
+		}
+	}
+
+	done, err := db.appliedVersions(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, m := range migrations {
+		if _, ok := done[m.version]; ok {
+			continue
+		}
+		for _, statement := range m.statements(db.Dialect) {
+			if _, err := tx.ExecContext(ctx, statement); err != nil {
+				return applied, fmt.Errorf("migration %d %q: %w", m.version, m.description, err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO schema_migrations (version, description, applied_at) VALUES ($1, $2, $3)`,
+			m.version, m.description, timestamp(time.Now()),
+		); err != nil {
+			return applied, err
+		}
+		slog.InfoContext(ctx, "Applied migration", "version", m.version, "description", m.description)
+		applied++
+	}
+
+	return applied, tx.Commit()
+}
+
+// MigrationStatus lists every schema version, applied or not
+func (db *DB) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	done, err := db.appliedVersions(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.version, Description: m.description}
+		if appliedAt, ok := done[m.version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// begin starts a transaction, which takes the write lock at once on SQLite
+func (db *DB) begin(ctx context.Context) (tx *sql.Tx, err error) {
+	err = db.waitBusy(ctx, func() (err error) {
+		tx, err = db.BeginTx(ctx, nil)
+		return err
+	})
+	return tx, err
+}
+
+// appliedVersions creates the bookkeeping table on first use
+func (db *DB) appliedVersions(ctx context.Context, tx *sql.Tx) (map[int]time.Time, error) {
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		description TEXT NOT NULL,
+		applied_at `+db.Dialect.timestampType()+` NOT NULL
+	)`); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		done[version] = appliedAt.UTC()
+	}
+	return done, rows.Err()
+}
@@ -0,0 +1,161 @@
+// Package sqlstore implements the repository interfaces on SQL databases, for
+// installs that would rather not run MongoDB. The same schema runs on
+// PostgreSQL (through pgx) and on SQLite (through the pure Go
+// modernc.org/sqlite); the dialects only differ in column types and error
+// codes.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Dialect is a supported database, named like the STORAGE_BACKEND value
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+// sqliteDefaults are added to SQLite DSNs that do not mention their key:
+// readers do not block the writer, transactions take the write lock up front
+// so migrations serialize and times are written in a format that sorts in
+// time order. SQLite's busy timeout stays 0, see waitBusy
+var sqliteDefaults = []struct{ key, param string }{
+	{"journal_mode", "_pragma=journal_mode(WAL)"},
+	{"_txlock", "_txlock=immediate"},
+	{"_time_format", "_time_format=sqlite"},
+}
+
+const (
+	// busyTimeout bounds how long a SQLite statement waits for another
+	// writer to release the database
+	busyTimeout = 5 * time.Second
+	// maxBusyBackoff caps the wait between attempts
+	maxBusyBackoff = 50 * time.Millisecond
+)
+
+// DB is a connection pool with its dialect
+type DB struct {
+	*sql.DB
+	Dialect Dialect
+}
+
+// Open connects to dsn, a postgres:// URL or keyword/value string for
+// Postgres and a file path or file: URI for SQLite, and pings it
+func Open(ctx context.Context, dialect Dialect, dsn string) (*DB, error) {
+	var driver string
+	switch dialect {
+	case Postgres:
+		driver = "pgx"
+	case SQLite:
+		driver = "sqlite"
+		dsn = sqliteDSN(dsn)
+	default:
+		return nil, fmt.Errorf("sqlstore: unknown dialect %q", dialect)
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &DB{DB: db, Dialect: dialect}, nil
+}
+
+func sqliteDSN(dsn string) string {
+	path, query, _ := strings.Cut(dsn, "?")
+	params := make([]string, 0, len(sqliteDefaults))
+	for _, d := range sqliteDefaults {
+		if !strings.Contains(query, d.key) {
+			params = append(params, d.param)
+		}
+	}
+	if query != "" {
+		params = append(params, query)
+	}
+	if len(params) == 0 {
+		return path
+	}
+	return path + "?" + strings.Join(params, "&")
+}
+
+// timestampType keeps time zones in Postgres; SQLite stores text and
+// the driver parses columns declared TIMESTAMP back into time.Time
+func (d Dialect) timestampType() string {
+	if d == Postgres {
+		return "TIMESTAMPTZ"
+	}
+	return "TIMESTAMP"
+}
+
+// binaryOrder sorts text by code point like MongoDB, whatever the collation
+// of the Postgres database
+func (d Dialect) binaryOrder(column string) string {
+	if d == Postgres {
+		return column + ` COLLATE "C"`
+	}
+	return column
+}
+
+func (d Dialect) isDuplicateKey(err error) bool {
+	if d == Postgres {
+		var pgErr *pgconn.PgError
+		return errors.As(err, &pgErr) && pgErr.Code == "23505"
+	}
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	code := sqliteErr.Code()
+	return code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+}
+
+// waitBusy runs fn again while SQLite reports that another connection holds
+// the lock, for up to busyTimeout. SQLite's own busy timeout sleeps without
+// checking for interrupts, so a request deadline could not end it; waiting
+// here stops as soon as ctx ends
+func (db *DB) waitBusy(ctx context.Context, fn func() error) error {
+	if db.Dialect != SQLite {
+		return fn()
+	}
+
+	deadline := time.Now().Add(busyTimeout)
+	backoff := time.Millisecond
+	for {
+		err := fn()
+		if !isBusy(err) || time.Now().After(deadline) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Join(ctx.Err(), err)
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxBusyBackoff)
+	}
+}
+
+func isBusy(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code()&0xff == sqlite3.SQLITE_BUSY
+}
+
+// timestamp is the value stored for t: UTC, so SQLite text compares in time
+// order, and microseconds, the precision of Postgres
+func timestamp(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
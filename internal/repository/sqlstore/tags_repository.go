@@ -0,0 +1,300 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"pre-test-gallery-service/internal/model"
+	"pre-test-gallery-service/internal/repository"
+	"pre-test-gallery-service/pkg/tenant"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const tagColumns = "id, tenant_id, name, created_at, updated_at"
+
+// streamTimeout bounds a Stream whose caller set no deadline
+const streamTimeout = 10 * time.Minute
+
+type tagsRepository struct {
+	db *DB
+}
+
+// NewTagsRepository stores tags in the tags table; IDs keep the ObjectID
+// format so they stay valid when data moves between backends
+func NewTagsRepository(db *DB) repository.TagsRepository {
+	return &tagsRepository{db: db}
+}
+
+func (r *tagsRepository) FindAll(ctx context.Context, filter repository.TagsFilter) (tags []model.Tags, err error) {
+	ctx, finish := startOperation(ctx, r.db.Dialect, "tags", "select")
+	defer func() { finish(err) }()
+
+	// ObjectIDs start with their creation time, like MongoDB's natural order
+	rows, err := r.query(ctx, filter, "ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags = make([]model.Tags, 0)
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+func (r *tagsRepository) FindOne(ctx context.Context, filter repository.TagsFilter) (_ *model.Tags, err error) {
+	ctx, finish := startOperation(ctx, r.db.Dialect, "tags", "selectOne")
+	defer func() { finish(err) }()
+
+	rows, err := r.query(ctx, filter, "ORDER BY id LIMIT 1")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	tag, err := scanTag(rows)
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (r *tagsRepository) Stream(ctx context.Context, filter repository.TagsFilter) (_ repository.TagsCursor, err error) {
+	ctx, finish := startOperation(ctx, r.db.Dialect, "tags", "select")
+	defer func() { finish(err) }()
+
+	// database/sql closes rows when the context of the query ends; like a
+	// MongoDB cursor, this one is instead stopped by the context given to Next.
+	// The caller's deadline still holds so a stalled query frees its connection
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(streamTimeout)
+	}
+	queryCtx, cancel := context.WithDeadline(context.WithoutCancel(ctx), deadline)
+
+	rows, err := r.query(queryCtx, filter, "ORDER BY "+r.db.Dialect.binaryOrder("name"))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &tagsCursor{rows: rows, cancel: cancel}, nil
+}
+
+func (r *tagsRepository) Create(ctx context.Context, tags *model.Tags) (err error) {
+	ctx, finish := startOperation(ctx, r.db.Dialect, "tags", "insert")
+	defer func() { finish(err) }()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := timestamp(time.Now())
+	// Imported tags keep their original creation time
+	createdAt := now
+	if !tags.CreatedAt.IsZero() {
+		createdAt = timestamp(tags.CreatedAt)
+	}
+
+	id := primitive.NewObjectID()
+	_, err = r.exec(ctx,
+		`INSERT INTO tags (id, tenant_id, name, name_folded, slug, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		id.Hex(), tenantID, tags.Name, repository.FoldName(tags.Name), repository.Slugify(tags.Name), createdAt, now,
+	)
+	if err != nil {
+		return r.writeError(err)
+	}
+
+	tags.ID = id
+	tags.TenantID = tenantID
+	tags.CreatedAt = createdAt
+	tags.UpdatedAt = now
+	return nil
+}
+
+func (r *tagsRepository) Update(ctx context.Context, tags *model.Tags) (err error) {
+	ctx, finish := startOperation(ctx, r.db.Dialect, "tags", "update")
+	defer func() { finish(err) }()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := timestamp(time.Now())
+	result, err := r.exec(ctx,
+		`UPDATE tags SET name = $1, name_folded = $2, slug = $3, created_at = $4, updated_at = $5 WHERE tenant_id = $6 AND id = $7`,
+		tags.Name, repository.FoldName(tags.Name), repository.Slugify(tags.Name), timestamp(tags.CreatedAt), now, tenantID, tags.ID.Hex(),
+	)
+	if err != nil {
+		return r.writeError(err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return repository.ErrNotFound
+	}
+
+	tags.UpdatedAt = now
+	return nil
+}
+
+func (r *tagsRepository) Delete(ctx context.Context, id primitive.ObjectID) (err error) {
+	ctx, finish := startOperation(ctx, r.db.Dialect, "tags", "delete")
+	defer func() { finish(err) }()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.exec(ctx, `DELETE FROM tags WHERE tenant_id = $1 AND id = $2`, tenantID, id.Hex())
+	return err
+}
+
+// query selects the tags of the context's tenant matching filter
+func (r *tagsRepository) query(ctx context.Context, filter repository.TagsFilter, suffix string) (*sql.Rows, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &where{}
+	w.add("tenant_id = %s", tenantID)
+	if !filter.ID.IsZero() {
+		w.add("id = %s", filter.ID.Hex())
+	}
+	if filter.Name != "" {
+		// Folded in Go, as SQLite's lower() only knows ASCII letters
+		w.add("name_folded = %s", repository.FoldName(filter.Name))
+	}
+	if filter.Slug != "" {
+		w.add("slug = %s", repository.Slugify(filter.Slug))
+	}
+	if filter.NamePrefix != "" {
+		// LIKE ignores case in SQLite, substr compares exactly in both
+		w.add("substr(name, 1, %s) = %s", utf8.RuneCountInString(filter.NamePrefix), filter.NamePrefix)
+	}
+	w.addRange("created_at", filter.CreatedAt)
+	w.addRange("updated_at", filter.UpdatedAt)
+
+	var rows *sql.Rows
+	err = r.db.waitBusy(ctx, func() (err error) {
+		rows, err = r.db.QueryContext(ctx, "SELECT "+tagColumns+" FROM tags WHERE "+strings.Join(w.conditions, " AND ")+" "+suffix, w.args...)
+		return err
+	})
+	return rows, err
+}
+
+func (r *tagsRepository) exec(ctx context.Context, query string, args ...any) (result sql.Result, err error) {
+	err = r.db.waitBusy(ctx, func() (err error) {
+		result, err = r.db.ExecContext(ctx, query, args...)
+		return err
+	})
+	return result, err
+}
+
+func (r *tagsRepository) writeError(err error) error {
+	if r.db.Dialect.isDuplicateKey(err) {
+		return fmt.Errorf("%w: %w", repository.ErrDuplicateKey, err)
+	}
+	return err
+}
+
+// where builds conditions with numbered placeholders. SQLite reads $1 as a
+// named parameter, so placeholders are numbered in order of appearance and
+// bind positionally in both dialects
+type where struct {
+	conditions []string
+	args       []any
+}
+
+func (w *where) add(condition string, args ...any) {
+	placeholders := make([]any, len(args))
+	for i, arg := range args {
+		w.args = append(w.args, arg)
+		placeholders[i] = fmt.Sprintf("$%d", len(w.args))
+	}
+	w.conditions = append(w.conditions, fmt.Sprintf(condition, placeholders...))
+}
+
+func (w *where) addRange(column string, r repository.TimeRange) {
+	if !r.From.IsZero() {
+		w.add(column+" >= %s", timestamp(r.From))
+	}
+	if !r.To.IsZero() {
+		w.add(column+" < %s", timestamp(r.To))
+	}
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTag(row scanner) (model.Tags, error) {
+	var tag model.Tags
+	var id string
+	if err := row.Scan(&id, &tag.TenantID, &tag.Name, &tag.CreatedAt, &tag.UpdatedAt); err != nil {
+		return model.Tags{}, err
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return model.Tags{}, fmt.Errorf("tag id %q: %w", id, err)
+	}
+	tag.ID = objectID
+	tag.CreatedAt = tag.CreatedAt.UTC()
+	tag.UpdatedAt = tag.UpdatedAt.UTC()
+	return tag, nil
+}
+
+// tagsCursor adapts sql.Rows to TagsCursor
+type tagsCursor struct {
+	rows   *sql.Rows
+	cancel context.CancelFunc
+	err    error
+}
+
+func (c *tagsCursor) Next(ctx context.Context) bool {
+	if err := ctx.Err(); err != nil {
+		c.err = err
+		return false
+	}
+	return c.rows.Next()
+}
+
+func (c *tagsCursor) Decode(tag *model.Tags) error {
+	decoded, err := scanTag(c.rows)
+	if err != nil {
+		return err
+	}
+	*tag = decoded
+	return nil
+}
+
+func (c *tagsCursor) Err() error {
+	if c.err != nil {
+		return c.err
+	}
+	return c.rows.Err()
+}
+
+func (c *tagsCursor) Close(ctx context.Context) error {
+	defer c.cancel()
+	return c.rows.Close()
+}
@@ -0,0 +1,169 @@
+package sqlstore_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"pre-test-gallery-service/internal/model"
+	"pre-test-gallery-service/internal/repository"
+	"pre-test-gallery-service/internal/repository/repotest"
+	"pre-test-gallery-service/internal/repository/sqlstore"
+	"pre-test-gallery-service/pkg/tenant"
+)
+
+func TestSQLiteTagsRepositoryContract(t *testing.T) {
+	repotest.TagsRepository(t, func(t *testing.T) repository.TagsRepository {
+		return sqlstore.NewTagsRepository(openSQLite(t))
+	})
+}
+
+// TestPostgresTagsRepositoryContract runs in a fresh schema of the database
+// at POSTGRES_TEST_URL and is skipped without one
+func TestPostgresTagsRepositoryContract(t *testing.T) {
+	dsn := os.Getenv("POSTGRES_TEST_URL")
+	if dsn == "" {
+		t.Skip("set POSTGRES_TEST_URL to run the Postgres contract tests")
+	}
+
+	ctx := context.Background()
+	admin, err := sqlstore.Open(ctx, sqlstore.Postgres, dsn)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { _ = admin.Close() })
+
+	var schemas atomic.Int64
+	repotest.TagsRepository(t, func(t *testing.T) repository.TagsRepository {
+		schema := fmt.Sprintf("gallery_repotest_%d_%d", os.Getpid(), schemas.Add(1))
+		if _, err := admin.ExecContext(ctx, "CREATE SCHEMA "+schema); err != nil {
+			t.Fatalf("create schema: %v", err)
+		}
+		t.Cleanup(func() { _, _ = admin.ExecContext(context.Background(), "DROP SCHEMA "+schema+" CASCADE") })
+
+		u, err := url.Parse(dsn)
+		if err != nil {
+			t.Fatalf("POSTGRES_TEST_URL: %v", err)
+		}
+		query := u.Query()
+		query.Set("search_path", schema)
+		u.RawQuery = query.Encode()
+
+		return sqlstore.NewTagsRepository(openMigrated(t, sqlstore.Postgres, u.String()))
+	})
+}
+
+func TestMigrateIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+
+	applied, err := db.Migrate(ctx)
+	if err != nil || applied != 0 {
+		t.Fatalf("second Migrate = %d, %v, want nothing to apply", applied, err)
+	}
+
+	statuses, err := db.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatalf("MigrationStatus: %v", err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			t.Errorf("migration %d %q is not applied", status.Version, status.Description)
+		}
+	}
+}
+
+// TestSQLiteWaitsForWriter holds the write lock on another connection: a
+// write ends with its context, and succeeds once the lock is released
+func TestSQLiteWaitsForWriter(t *testing.T) {
+	db := openSQLite(t)
+	repo := sqlstore.NewTagsRepository(db)
+	ctx := tenant.WithTenant(context.Background(), "acme")
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Conn: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		t.Fatalf("lock database: %v", err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = repo.Create(timeoutCtx, &model.Tags{Name: "street"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Create = %v, want the context deadline", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Create returned after %s, want about 100ms", elapsed)
+	}
+
+	time.AfterFunc(100*time.Millisecond, func() { _, _ = conn.ExecContext(ctx, "ROLLBACK") })
+	if err := repo.Create(ctx, &model.Tags{Name: "street"}); err != nil {
+		t.Fatalf("Create after the lock is released: %v", err)
+	}
+}
+
+// TestStreamKeepsDeadline leaves a stream open past the deadline of the
+// context it was opened with: its connection must go back to the pool
+func TestStreamKeepsDeadline(t *testing.T) {
+	db := openSQLite(t)
+	repo := sqlstore.NewTagsRepository(db)
+	ctx := tenant.WithTenant(context.Background(), "acme")
+	for _, name := range []string{"black", "street", "white"} {
+		if err := repo.Create(ctx, &model.Tags{Name: name}); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	openCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	cursor, err := repo.Stream(openCtx, repository.TagsFilter{})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	defer cursor.Close(ctx)
+	if !cursor.Next(ctx) {
+		t.Fatalf("Next: %v", cursor.Err())
+	}
+	if db.Stats().InUse != 1 {
+		t.Fatalf("InUse = %d while streaming, want 1", db.Stats().InUse)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for db.Stats().InUse != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("stream still holds its connection after the deadline")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func openSQLite(t *testing.T) *sqlstore.DB {
+	t.Helper()
+	return openMigrated(t, sqlstore.SQLite, filepath.Join(t.TempDir(), "gallery.db"))
+}
+
+func openMigrated(t *testing.T, dialect sqlstore.Dialect, dsn string) *sqlstore.DB {
+	t.Helper()
+
+	ctx := context.Background()
+	db, err := sqlstore.Open(ctx, dialect, dsn)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	if _, err := db.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	return db
+}
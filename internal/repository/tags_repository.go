@@ -3,20 +3,43 @@ package repository
 import (
 	"context"
 	"pre-test-gallery-service/internal/model"
+	"pre-test-gallery-service/pkg/tenant"
+	"regexp"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type TagsRepository interface {
-	FindAll(ctx context.Context, query bson.M) ([]model.Tags, error)
-	FindOne(ctx context.Context, query bson.M) (*model.Tags, error)
+	FindAll(ctx context.Context, filter TagsFilter) ([]model.Tags, error)
+	// FindOne returns nil, nil when no tag matches
+	FindOne(ctx context.Context, filter TagsFilter) (*model.Tags, error)
+	// Stream returns the matching tags ordered by name without loading them
+	// all in memory; the caller must close the cursor
+	Stream(ctx context.Context, filter TagsFilter) (TagsCursor, error)
 	Create(ctx context.Context, tags *model.Tags) error
+	// Update replaces the name and timestamps of an existing tag; it returns
+	// ErrNotFound when the tag does not exist
+	Update(ctx context.Context, tags *model.Tags) error
 	Delete(ctx context.Context, id primitive.ObjectID) error
 }
 
+// TagsCursor iterates over a stream of tags
+type TagsCursor interface {
+	Next(ctx context.Context) bool
+	Decode(tag *model.Tags) error
+	Err() error
+	Close(ctx context.Context) error
+}
+
+// TagNameCollation compares tag names ignoring case. The unique name index
+// is built with it, and queries on the name must use it to match the index
+var TagNameCollation = &options.Collation{Locale: "en", Strength: 2}
+
 type tagsRepository struct {
 	collection *mongo.Collection
 }
@@ -27,9 +50,16 @@ func NewTagsRepository(db *mongo.Database) TagsRepository {
 	}
 }
 
-func (r *tagsRepository) FindAll(ctx context.Context, query bson.M) ([]model.Tags, error) {
-	var tags []model.Tags
-	cursor, err := r.collection.Find(ctx, query)
+func (r *tagsRepository) FindAll(ctx context.Context, tagsFilter TagsFilter) (tags []model.Tags, err error) {
+	ctx, finish := startOperation(ctx, "tags", "find")
+	defer func() { finish(err) }()
+
+	filter, err := tenantFilter(ctx, tagsQuery(tagsFilter))
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetCollation(tagsCollation(tagsFilter)))
 	if err != nil {
 		return nil, err
 	}
@@ -39,9 +69,17 @@ func (r *tagsRepository) FindAll(ctx context.Context, query bson.M) ([]model.Tag
 	return tags, nil
 }
 
-func (r *tagsRepository) FindOne(ctx context.Context, query bson.M) (*model.Tags, error) {
+func (r *tagsRepository) FindOne(ctx context.Context, tagsFilter TagsFilter) (_ *model.Tags, err error) {
+	ctx, finish := startOperation(ctx, "tags", "findOne")
+	defer func() { finish(err) }()
+
+	filter, err := tenantFilter(ctx, tagsQuery(tagsFilter))
+	if err != nil {
+		return nil, err
+	}
+
 	var result model.Tags
-	err := r.collection.FindOne(ctx, query).Decode(&result)
+	err = r.collection.FindOne(ctx, filter, options.FindOne().SetCollation(tagsCollation(tagsFilter))).Decode(&result)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
@@ -51,16 +89,168 @@ func (r *tagsRepository) FindOne(ctx context.Context, query bson.M) (*model.Tags
 	return &result, nil
 }
 
-func (r *tagsRepository) Create(ctx context.Context, tags *model.Tags) error {
+func (r *tagsRepository) Stream(ctx context.Context, tagsFilter TagsFilter) (_ TagsCursor, err error) {
+	ctx, finish := startOperation(ctx, "tags", "find")
+	defer func() { finish(err) }()
+
+	filter, err := tenantFilter(ctx, tagsQuery(tagsFilter))
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}}).SetCollation(tagsCollation(tagsFilter))
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &tagsCursor{cursor: cursor}, nil
+}
+
+func (r *tagsRepository) Create(ctx context.Context, tags *model.Tags) (err error) {
+	ctx, finish := startOperation(ctx, "tags", "insertOne")
+	defer func() { finish(err) }()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
 	tags.ID = primitive.NewObjectID()
-	tags.CreatedAt = time.Now()
-	tags.UpdatedAt = time.Now()
+	tags.TenantID = tenantID
+	// Imported tags keep their original creation time
+	if tags.CreatedAt.IsZero() {
+		tags.CreatedAt = now
+	}
+	tags.UpdatedAt = now
 
-	_, err := r.collection.InsertOne(ctx, tags)
+	_, err = r.collection.InsertOne(ctx, tags)
 	return err
 }
 
-func (r *tagsRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
-	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+func (r *tagsRepository) Update(ctx context.Context, tags *model.Tags) (err error) {
+	ctx, finish := startOperation(ctx, "tags", "updateOne")
+	defer func() { finish(err) }()
+
+	filter, err := tenantFilter(ctx, bson.M{"_id": tags.ID})
+	if err != nil {
+		return err
+	}
+
+	tags.UpdatedAt = time.Now()
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{
+		"name":       tags.Name,
+		"created_at": tags.CreatedAt,
+		"updated_at": tags.UpdatedAt,
+	}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *tagsRepository) Delete(ctx context.Context, id primitive.ObjectID) (err error) {
+	ctx, finish := startOperation(ctx, "tags", "deleteOne")
+	defer func() { finish(err) }()
+
+	filter, err := tenantFilter(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.DeleteOne(ctx, filter)
 	return err
 }
+
+// tagsCollation is TagNameCollation when filter has a name, and nil, the
+// binary comparison, otherwise so names stream in code point order
+func tagsCollation(filter TagsFilter) *options.Collation {
+	if filter.Name != "" {
+		return TagNameCollation
+	}
+	return nil
+}
+
+// tagsQuery translates filter to a query on the tags collection
+func tagsQuery(filter TagsFilter) bson.M {
+	query := bson.M{}
+	if !filter.ID.IsZero() {
+		query["_id"] = filter.ID
+	}
+
+	// The name conditions are ANDed since each of them needs the name key
+	var names bson.A
+	if filter.Name != "" {
+		names = append(names, filter.Name)
+	}
+	if filter.NamePrefix != "" {
+		names = append(names, primitive.Regex{Pattern: "^" + regexp.QuoteMeta(filter.NamePrefix)})
+	}
+	if filter.Slug != "" {
+		// The words of the slug in any case, separated by runs of separators
+		// and with any around them
+		words := strings.Split(Slugify(filter.Slug), "-")
+		for i, word := range words {
+			words[i] = regexp.QuoteMeta(word)
+		}
+		pattern := "^[ _-]*" + strings.Join(words, "[ _-]+") + "[ _-]*$"
+		names = append(names, primitive.Regex{Pattern: pattern, Options: "i"})
+	}
+	switch len(names) {
+	case 0:
+	case 1:
+		query["name"] = names[0]
+	default:
+		and := make(bson.A, 0, len(names))
+		for _, name := range names {
+			and = append(and, bson.M{"name": name})
+		}
+		query["$and"] = and
+	}
+
+	if r := timeRangeQuery(filter.CreatedAt); r != nil {
+		query["created_at"] = r
+	}
+	if r := timeRangeQuery(filter.UpdatedAt); r != nil {
+		query["updated_at"] = r
+	}
+	return query
+}
+
+func timeRangeQuery(r TimeRange) bson.M {
+	if r.IsZero() {
+		return nil
+	}
+	query := bson.M{}
+	if !r.From.IsZero() {
+		query["$gte"] = r.From
+	}
+	if !r.To.IsZero() {
+		query["$lt"] = r.To
+	}
+	return query
+}
+
+// tagsCursor adapts a driver cursor to TagsCursor
+type tagsCursor struct {
+	cursor *mongo.Cursor
+}
+
+func (c *tagsCursor) Next(ctx context.Context) bool {
+	return c.cursor.Next(ctx)
+}
+
+func (c *tagsCursor) Decode(tag *model.Tags) error {
+	return c.cursor.Decode(tag)
+}
+
+func (c *tagsCursor) Err() error {
+	return c.cursor.Err()
+}
+
+func (c *tagsCursor) Close(ctx context.Context) error {
+	return c.cursor.Close(ctx)
+}
@@ -0,0 +1,33 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"pre-test-gallery-service/internal/migrations"
+	"pre-test-gallery-service/internal/repository"
+	"pre-test-gallery-service/internal/repository/repotest"
+	"pre-test-gallery-service/pkg/migrate"
+	"pre-test-gallery-service/pkg/mongotest"
+)
+
+// TestTagsRepositoryContract runs against MONGO_TEST_URI, or a throwaway
+// mongod when one is on the PATH, and is skipped otherwise
+func TestTagsRepositoryContract(t *testing.T) {
+	client := mongotest.Client(t)
+
+	repotest.TagsRepository(t, func(t *testing.T) repository.TagsRepository {
+		db := mongotest.Database(t, client)
+
+		// The unique (tenant_id, name) index comes from the migrations
+		runner, err := migrate.New(db, migrations.All(migrations.LegacyTenant), time.Minute)
+		if err != nil {
+			t.Fatalf("migrations: %v", err)
+		}
+		if _, err := runner.Up(context.Background()); err != nil {
+			t.Fatalf("migrate up: %v", err)
+		}
+		return repository.NewTagsRepository(db)
+	})
+}
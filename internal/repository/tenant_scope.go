@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"pre-test-gallery-service/pkg/tenant"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// tenantFilter copies the query and pins it to the tenant of the context, so
+// callers can never read or write documents of another workspace
+func tenantFilter(ctx context.Context, query bson.M) (bson.M, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{}
+	for k, v := range query {
+		filter[k] = v
+	}
+	filter["tenant_id"] = tenantID
+	return filter, nil
+}
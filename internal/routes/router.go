@@ -1,12 +1,20 @@
 package routes
 
 import (
+	"database/sql"
 	"pre-test-gallery-service/internal/config"
 	"pre-test-gallery-service/internal/handlers"
+	"pre-test-gallery-service/pkg/auth"
+	"pre-test-gallery-service/pkg/health"
+	"pre-test-gallery-service/pkg/metrics"
 	"pre-test-gallery-service/pkg/middleware"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/skip"
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/mongo"
 
 	_ "pre-test-gallery-service/docs"
 
@@ -14,23 +22,87 @@ import (
 )
 
 type Application struct {
-	App         *fiber.App
-	TagsHandler *handlers.TagsHandler
-	Config      *config.Config
+	App           *fiber.App
+	TagsHandler   *handlers.TagsHandler
+	AuthHandler   *handlers.AuthHandler
+	HealthHandler *handlers.HealthHandler
+	// HealthChecks backs /readyz and is drained on shutdown
+	HealthChecks *health.Registry
+	TokenIssuer  *auth.TokenIssuer
+	RateLimiters *middleware.RateLimiters
+	// MongoClient or SQLDB is set, depending on STORAGE_BACKEND
+	MongoClient *mongo.Client
+	SQLDB       *sql.DB
+	RedisClient *redis.Client
+	// RequestTimeout bounds the context of every API request
+	RequestTimeout time.Duration
+	Config         *config.Config
 }
 
 func (app *Application) SetupRoutes() {
 	// Swagger route
 	app.App.Get("/swagger/*", fiberSwagger.WrapHandler)
 
+	// Prometheus metrics, behind METRICS_TOKEN when set; without it they
+	// are only served outside production
+	if app.Config.MetricsToken != "" {
+		app.App.Get("/metrics", middleware.RequireBearerToken(app.Config.MetricsToken), metrics.Handler())
+	} else if !app.Config.IsProduction() {
+		app.App.Get("/metrics", metrics.Handler())
+	}
+
+	// Health probes
+	app.App.Get("/healthz", app.HealthHandler.Liveness)
+	app.App.Get("/readyz", app.HealthHandler.Readiness)
+
 	// API routes
 	v1 := app.App.Group("/api/v1")
-	// Rate limit (You can use route by route)
-	v1.Use(middleware.RateLimit(100, time.Minute))
+
+	// Request scoped context with deadline, and bodies read up to BodyLimit.
+	// Imports read their body as a stream under their own deadline instead
+	requestTimeout := app.RequestTimeout
+	if requestTimeout <= 0 {
+		requestTimeout = 5 * time.Second
+	}
+	isImport := func(c *fiber.Ctx) bool {
+		return c.Method() == fiber.MethodPost && strings.EqualFold(strings.TrimSuffix(c.Path(), "/"), "/api/v1/tags/import")
+	}
+	v1.Use(skip.New(middleware.RequestContext(requestTimeout), isImport))
+	v1.Use(skip.New(middleware.BufferBody(), isImport))
+
+	// Rate limit per route, see RATE_LIMIT_POLICIES
+	limits := app.RateLimiters
+	if limits == nil {
+		limits = middleware.NewRateLimiters(&middleware.RateLimitPolicies{
+			Default: middleware.DefaultRateLimitPolicy,
+		}, middleware.MemoryStoreFactory)
+	}
+
+	// Write access is open unless SSO login is configured
+	canWrite := func(c *fiber.Ctx) error { return c.Next() }
 
 	// Auth routes
-	tags := v1.Group("/tags")
-	tags.Get("/", app.TagsHandler.GetAllTags)
-	tags.Post("/", app.TagsHandler.CreateTags)
-	tags.Delete("/:tag", app.TagsHandler.DeleteTags)
+	if app.AuthHandler != nil {
+		v1.Use(middleware.Authenticate(app.TokenIssuer))
+		canWrite = middleware.RequireRole(auth.RoleEditor, auth.RoleAdmin)
+
+		authRoutes := v1.Group("/auth")
+		authRoutes.Get("/oidc/login", limits.For("auth.login"), app.AuthHandler.OIDCLogin)
+		authRoutes.Get("/oidc/callback", limits.For("auth.callback"), app.AuthHandler.OIDCCallback)
+	}
+
+	// Tenant scoped routes
+	tenantHeader := app.Config.TenantHeader
+	if tenantHeader == "" {
+		tenantHeader = "X-Tenant-ID"
+	}
+	workspace := middleware.ResolveTenant(tenantHeader, app.Config.DefaultTenant, app.AuthHandler != nil)
+
+	// Tags routes
+	tags := v1.Group("/tags", workspace)
+	tags.Get("/", limits.For("tags.list"), app.TagsHandler.GetAllTags)
+	tags.Post("/", limits.For("tags.create"), canWrite, app.TagsHandler.CreateTags)
+	tags.Get("/export", limits.For("tags.export"), app.TagsHandler.ExportTags)
+	tags.Post("/import", middleware.RequestContext(handlers.ImportTimeout), limits.For("tags.import"), canWrite, app.TagsHandler.ImportTags)
+	tags.Delete("/:tag", limits.For("tags.delete"), canWrite, app.TagsHandler.DeleteTags)
 }
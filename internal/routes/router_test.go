@@ -0,0 +1,59 @@
+package routes
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"pre-test-gallery-service/internal/config"
+	"pre-test-gallery-service/internal/handlers"
+	"pre-test-gallery-service/internal/repository/memory"
+	"pre-test-gallery-service/internal/service"
+	"pre-test-gallery-service/pkg/health"
+	"pre-test-gallery-service/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestMetricsAccess(t *testing.T) {
+	tests := []struct {
+		name          string
+		cfg           config.Config
+		authorization string
+		want          int
+	}{
+		{name: "development without token", cfg: config.Config{ServerState: "development"}, want: fiber.StatusOK},
+		{name: "production without token", cfg: config.Config{ServerState: "production"}, want: fiber.StatusNotFound},
+		{name: "token missing", cfg: config.Config{MetricsToken: "s3cret"}, want: fiber.StatusUnauthorized},
+		{name: "token wrong", cfg: config.Config{MetricsToken: "s3cret"}, authorization: "Bearer guess", want: fiber.StatusUnauthorized},
+		{name: "not a bearer token", cfg: config.Config{MetricsToken: "s3cret"}, authorization: "Basic czNjcmV0", want: fiber.StatusUnauthorized},
+		{name: "token matches", cfg: config.Config{ServerState: "production", MetricsToken: "s3cret"}, authorization: "Bearer s3cret", want: fiber.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New(fiber.Config{ErrorHandler: utils.ErrorHandler})
+			application := &Application{
+				App:           app,
+				TagsHandler:   handlers.NewTagsHandler(service.NewTagsService(memory.NewTagsRepository())),
+				HealthHandler: handlers.NewHealthHandler(health.NewRegistry(time.Second)),
+				Config:        &tt.cfg,
+			}
+			application.SetupRoutes()
+
+			req := httptest.NewRequest(fiber.MethodGet, "/metrics", nil)
+			if tt.authorization != "" {
+				req.Header.Set(fiber.HeaderAuthorization, tt.authorization)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("app.Test: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
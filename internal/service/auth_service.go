@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"pre-test-gallery-service/pkg/auth"
+	"pre-test-gallery-service/pkg/dto"
+	"pre-test-gallery-service/pkg/logger"
+	"pre-test-gallery-service/pkg/tracing"
+)
+
+type AuthService struct {
+	provider    *auth.OIDCProvider
+	tokenIssuer *auth.TokenIssuer
+}
+
+func NewAuthService(provider *auth.OIDCProvider, tokenIssuer *auth.TokenIssuer) *AuthService {
+	return &AuthService{
+		provider:    provider,
+		tokenIssuer: tokenIssuer,
+	}
+}
+
+// LoginURL returns the IdP authorization URL together with the state and
+// nonce the caller must keep until the callback
+func (s *AuthService) LoginURL() (string, string, string, error) {
+	state, err := auth.RandomString()
+	if err != nil {
+		return "", "", "", err
+	}
+	nonce, err := auth.RandomString()
+	if err != nil {
+		return "", "", "", err
+	}
+	return s.provider.AuthCodeURL(state, nonce), state, nonce, nil
+}
+
+func (s *AuthService) CompleteLogin(ctx context.Context, code, nonce string) (_ *dto.AuthResponse, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "AuthService.CompleteLogin")
+	defer func() { tracing.End(span, err) }()
+
+	identity, err := s.provider.Exchange(ctx, code, nonce)
+	if err != nil {
+		return nil, ErrLoginFailed.Wrap(err)
+	}
+
+	token, expiresAt, err := s.tokenIssuer.Issue(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info("User logged in", "user", identity.Subject, "role", identity.Role)
+
+	return &dto.AuthResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresAt:   expiresAt,
+		User: dto.AuthUser{
+			Subject: identity.Subject,
+			Email:   identity.Email,
+			Name:    identity.Name,
+			Role:    identity.Role,
+			Tenant:  identity.Tenant,
+		},
+	}, nil
+}
@@ -0,0 +1,12 @@
+package service
+
+import "pre-test-gallery-service/pkg/apperror"
+
+// Domain errors returned by the services; their codes are part of the API
+var (
+	ErrTagNotFound      = apperror.New(apperror.KindNotFound, "TAG_NOT_FOUND", "Tag not found")
+	ErrTagAlreadyExists = apperror.New(apperror.KindConflict, "TAG_ALREADY_EXISTS", "Tag already exists")
+	// ErrTagDuplicatedInFile marks import records repeating an earlier name
+	ErrTagDuplicatedInFile = apperror.New(apperror.KindInvalid, "TAG_DUPLICATED_IN_FILE", "Tag appears more than once in the file")
+	ErrLoginFailed         = apperror.New(apperror.KindUnauthorized, "AUTH_LOGIN_FAILED", "Login failed")
+)
@@ -5,9 +5,10 @@ import (
 	"pre-test-gallery-service/internal/model"
 	"pre-test-gallery-service/internal/repository"
 	"pre-test-gallery-service/pkg/dto"
+	"pre-test-gallery-service/pkg/logger"
+	"pre-test-gallery-service/pkg/tracing"
 	"time"
 
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -21,11 +22,27 @@ func NewTagsService(tagsRepo repository.TagsRepository) *TagsService {
 	}
 }
 
-func (s *TagsService) GetAllTags(ctx context.Context) ([]model.Tags, error) {
-	return s.tagsRepo.FindAll(ctx, bson.M{})
+// GetAllTags returns the tags of the current tenant matching filter; the
+// zero filter returns them all
+func (s *TagsService) GetAllTags(ctx context.Context, filter repository.TagsFilter) (tags []model.Tags, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TagsService.GetAllTags")
+	defer func() { tracing.End(span, err) }()
+
+	return s.tagsRepo.FindAll(ctx, filter)
 }
 
-func (s *TagsService) CreateTags(ctx context.Context, req dto.TagsRequest) (*model.Tags, error) {
+func (s *TagsService) CreateTags(ctx context.Context, req dto.TagsRequest) (_ *model.Tags, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TagsService.CreateTags")
+	defer func() { tracing.End(span, err) }()
+
+	existing, err := s.tagsRepo.FindOne(ctx, repository.TagByName(req.Name))
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrTagAlreadyExists
+	}
+
 	now := time.Now()
 	tag := &model.Tags{
 		ID:        primitive.NewObjectID(),
@@ -34,15 +51,48 @@ func (s *TagsService) CreateTags(ctx context.Context, req dto.TagsRequest) (*mod
 		UpdatedAt: now,
 	}
 	if err := s.tagsRepo.Create(ctx, tag); err != nil {
+		if repository.IsDuplicateKey(err) {
+			return nil, ErrTagAlreadyExists.Wrap(err)
+		}
 		return nil, err
 	}
+
+	logger.FromContext(ctx).Info("Tag created", "tag_id", tag.ID.Hex(), "name", tag.Name)
 	return tag, nil
 }
 
-func (s *TagsService) FindOneTags(ctx context.Context, query bson.M) (*model.Tags, error) {
-	return s.tagsRepo.FindOne(ctx, query)
+// FindOneTags returns the first tag matching filter, or nil when none does
+func (s *TagsService) FindOneTags(ctx context.Context, filter repository.TagsFilter) (_ *model.Tags, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TagsService.FindOneTags")
+	defer func() { tracing.End(span, err) }()
+
+	return s.tagsRepo.FindOne(ctx, filter)
 }
 
-func (s *TagsService) DeleteTags(ctx context.Context, id primitive.ObjectID) error {
-	return s.tagsRepo.Delete(ctx, id)
+// DeleteTagsByName deletes the tag with the given name in the current tenant
+func (s *TagsService) DeleteTagsByName(ctx context.Context, name string) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TagsService.DeleteTagsByName")
+	defer func() { tracing.End(span, err) }()
+
+	tag, err := s.tagsRepo.FindOne(ctx, repository.TagByName(name))
+	if err != nil {
+		return err
+	}
+	if tag == nil {
+		return ErrTagNotFound
+	}
+
+	return s.DeleteTags(ctx, tag.ID)
+}
+
+func (s *TagsService) DeleteTags(ctx context.Context, id primitive.ObjectID) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TagsService.DeleteTags")
+	defer func() { tracing.End(span, err) }()
+
+	if err := s.tagsRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	logger.FromContext(ctx).Info("Tag deleted", "tag_id", id.Hex())
+	return nil
 }
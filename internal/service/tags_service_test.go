@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"pre-test-gallery-service/internal/repository"
+	"pre-test-gallery-service/internal/repository/memory"
+	"pre-test-gallery-service/pkg/dto"
+	"pre-test-gallery-service/pkg/tenant"
+)
+
+func newTagsService(t *testing.T, names ...string) (*TagsService, context.Context) {
+	t.Helper()
+
+	ctx := tenant.WithTenant(context.Background(), "acme")
+	s := NewTagsService(memory.NewTagsRepository())
+	for _, name := range names {
+		if _, err := s.CreateTags(ctx, dto.TagsRequest{Name: name}); err != nil {
+			t.Fatalf("CreateTags(%q): %v", name, err)
+		}
+	}
+	return s, ctx
+}
+
+func tagNames(t *testing.T, s *TagsService, ctx context.Context) []string {
+	t.Helper()
+
+	tags, err := s.GetAllTags(ctx, repository.TagsFilter{})
+	if err != nil {
+		t.Fatalf("GetAllTags: %v", err)
+	}
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+func TestCreateTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		create   string
+		tenant   string
+		wantErr  error
+	}{
+		{name: "new tag", create: "street"},
+		{name: "existing tag", existing: []string{"street"}, create: "street", wantErr: ErrTagAlreadyExists},
+		{name: "same name in another tenant", existing: []string{"street"}, create: "street", tenant: "other"},
+		{name: "names ignore case", existing: []string{"street"}, create: "Street", wantErr: ErrTagAlreadyExists},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, ctx := newTagsService(t, tt.existing...)
+			if tt.tenant != "" {
+				ctx = tenant.WithTenant(ctx, tt.tenant)
+			}
+
+			tag, err := s.CreateTags(ctx, dto.TagsRequest{Name: tt.create})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateTags error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (tag.Name != tt.create || tag.ID.IsZero() || tag.CreatedAt.IsZero()) {
+				t.Fatalf("created %+v", tag)
+			}
+		})
+	}
+}
+
+func TestCreateTagsRequiresTenant(t *testing.T) {
+	s := NewTagsService(memory.NewTagsRepository())
+	if _, err := s.CreateTags(context.Background(), dto.TagsRequest{Name: "street"}); !errors.Is(err, tenant.ErrMissingTenant) {
+		t.Fatalf("CreateTags error = %v, want %v", err, tenant.ErrMissingTenant)
+	}
+}
+
+func TestDeleteTagsByName(t *testing.T) {
+	s, ctx := newTagsService(t, "street", "night")
+
+	if err := s.DeleteTagsByName(ctx, "street"); err != nil {
+		t.Fatalf("DeleteTagsByName: %v", err)
+	}
+	if err := s.DeleteTagsByName(ctx, "street"); !errors.Is(err, ErrTagNotFound) {
+		t.Fatalf("second DeleteTagsByName error = %v, want %v", err, ErrTagNotFound)
+	}
+	if err := s.DeleteTagsByName(tenant.WithTenant(ctx, "other"), "night"); !errors.Is(err, ErrTagNotFound) {
+		t.Fatalf("DeleteTagsByName in another tenant error = %v, want %v", err, ErrTagNotFound)
+	}
+
+	if names := tagNames(t, s, ctx); len(names) != 1 || names[0] != "night" {
+		t.Fatalf("tags after delete = %v", names)
+	}
+}
+
+func TestImportTags(t *testing.T) {
+	older := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	records := []dto.TagRecord{
+		{Name: "street", CreatedAt: &older},
+		{Name: "travel"},
+		{Name: "travel"},
+		{Name: "no/slash"},
+	}
+
+	tests := []struct {
+		strategy  ImportStrategy
+		dryRun    bool
+		wantCount map[ImportAction]int
+		wantNames []string
+	}{
+		{
+			strategy:  ImportSkip,
+			wantCount: map[ImportAction]int{ActionCreated: 1, ActionSkipped: 1, ActionInvalid: 2},
+			wantNames: []string{"street", "night", "travel"},
+		},
+		{
+			strategy:  ImportMerge,
+			wantCount: map[ImportAction]int{ActionCreated: 1, ActionUpdated: 1, ActionInvalid: 2},
+			wantNames: []string{"street", "night", "travel"},
+		},
+		{
+			strategy:  ImportOverwrite,
+			wantCount: map[ImportAction]int{ActionCreated: 1, ActionUpdated: 1, ActionDeleted: 1, ActionInvalid: 2},
+			wantNames: []string{"street", "travel"},
+		},
+		{
+			strategy:  ImportOverwrite,
+			dryRun:    true,
+			wantCount: map[ImportAction]int{ActionCreated: 1, ActionUpdated: 1, ActionDeleted: 1, ActionInvalid: 2},
+			wantNames: []string{"street", "night"},
+		},
+	}
+
+	for _, tt := range tests {
+		name := string(tt.strategy)
+		if tt.dryRun {
+			name += " dry run"
+		}
+		t.Run(name, func(t *testing.T) {
+			s, ctx := newTagsService(t, "street", "night")
+
+			report, err := s.ImportTags(ctx, Records(records), ImportOptions{Strategy: tt.strategy, DryRun: tt.dryRun})
+			if err != nil {
+				t.Fatalf("ImportTags: %v", err)
+			}
+
+			for _, action := range []ImportAction{ActionCreated, ActionUpdated, ActionUnchanged, ActionSkipped, ActionDeleted, ActionInvalid} {
+				if got := report.Count(action); got != tt.wantCount[action] {
+					t.Errorf("%s = %d, want %d", action, got, tt.wantCount[action])
+				}
+			}
+			if !errors.Is(report.Results[2].Err, ErrTagDuplicatedInFile) {
+				t.Errorf("repeated record error = %v", report.Results[2].Err)
+			}
+
+			names := tagNames(t, s, ctx)
+			if len(names) != len(tt.wantNames) {
+				t.Fatalf("tags = %v, want %v", names, tt.wantNames)
+			}
+			for i := range names {
+				if names[i] != tt.wantNames[i] {
+					t.Fatalf("tags = %v, want %v", names, tt.wantNames)
+				}
+			}
+		})
+	}
+}
+
+func TestImportTagsMergeKeepsMatchingTags(t *testing.T) {
+	s, ctx := newTagsService(t, "street")
+	tags, _ := s.GetAllTags(ctx, repository.TagsFilter{})
+	createdAt := tags[0].CreatedAt
+
+	report, err := s.ImportTags(ctx, Records([]dto.TagRecord{{Name: "street", CreatedAt: &createdAt}}), ImportOptions{Strategy: ImportMerge})
+	if err != nil {
+		t.Fatalf("ImportTags: %v", err)
+	}
+	if report.Count(ActionUnchanged) != 1 {
+		t.Fatalf("results = %+v, want the tag unchanged", report.Results)
+	}
+}
+
+func TestImportTagsMatchesNamesIgnoringCase(t *testing.T) {
+	s, ctx := newTagsService(t, "street")
+
+	records := []dto.TagRecord{{Name: "Street"}, {Name: "STREET"}}
+	report, err := s.ImportTags(ctx, Records(records), ImportOptions{Strategy: ImportSkip})
+	if err != nil {
+		t.Fatalf("ImportTags: %v", err)
+	}
+	if report.Count(ActionSkipped) != 1 || report.Count(ActionInvalid) != 1 {
+		t.Fatalf("skip results = %+v, want one skipped and one duplicate in the file", report.Results)
+	}
+
+	report, err = s.ImportTags(ctx, Records(records[:1]), ImportOptions{Strategy: ImportMerge})
+	if err != nil {
+		t.Fatalf("ImportTags: %v", err)
+	}
+	if report.Count(ActionUpdated) != 1 {
+		t.Fatalf("merge results = %+v, want the tag updated", report.Results)
+	}
+	if names := tagNames(t, s, ctx); len(names) != 1 || names[0] != "Street" {
+		t.Fatalf("tags = %v, want the name in the case of the file", names)
+	}
+}
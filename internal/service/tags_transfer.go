@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"pre-test-gallery-service/internal/model"
+	"pre-test-gallery-service/internal/repository"
+	"pre-test-gallery-service/pkg/dto"
+	"pre-test-gallery-service/pkg/logger"
+	"pre-test-gallery-service/pkg/tracing"
+	"pre-test-gallery-service/pkg/utils"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ImportStrategy decides what happens to tags that already exist
+type ImportStrategy string
+
+const (
+	// ImportSkip only creates missing tags
+	ImportSkip ImportStrategy = "skip"
+	// ImportMerge creates missing tags and updates existing ones with the
+	// fields given in the file
+	ImportMerge ImportStrategy = "merge"
+	// ImportOverwrite makes the taxonomy equal to the file: like merge, and
+	// tags missing from the file are deleted
+	ImportOverwrite ImportStrategy = "overwrite"
+)
+
+type ImportAction string
+
+const (
+	ActionCreated   ImportAction = "created"
+	ActionUpdated   ImportAction = "updated"
+	ActionUnchanged ImportAction = "unchanged"
+	ActionSkipped   ImportAction = "skipped"
+	ActionDeleted   ImportAction = "deleted"
+	ActionInvalid   ImportAction = "invalid"
+)
+
+type ImportOptions struct {
+	Strategy ImportStrategy
+	// DryRun reports what would change without writing
+	DryRun bool
+}
+
+// ImportResult is the outcome of one record; Line is 0 for deleted tags
+type ImportResult struct {
+	Line   int
+	Name   string
+	Action ImportAction
+	// Err explains why an invalid record was ignored
+	Err error
+}
+
+type ImportReport struct {
+	Strategy ImportStrategy
+	DryRun   bool
+	Results  []ImportResult
+}
+
+// Count returns how many records ended with action
+func (r *ImportReport) Count(action ImportAction) int {
+	n := 0
+	for _, result := range r.Results {
+		if result.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// ExportTags streams the tags of the current tenant ordered by name
+func (s *TagsService) ExportTags(ctx context.Context) (_ repository.TagsCursor, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TagsService.ExportTags")
+	defer func() { tracing.End(span, err) }()
+
+	return s.tagsRepo.Stream(ctx, repository.TagsFilter{})
+}
+
+// TagRecords yields the records of an import one at a time; Next returns
+// io.EOF after the last one
+type TagRecords interface {
+	Next() (dto.TagRecord, error)
+}
+
+// Records yields records already in memory
+func Records(records []dto.TagRecord) TagRecords {
+	return &recordList{records: records}
+}
+
+type recordList struct {
+	records []dto.TagRecord
+}
+
+func (l *recordList) Next() (dto.TagRecord, error) {
+	if len(l.records) == 0 {
+		return dto.TagRecord{}, io.EOF
+	}
+	record := l.records[0]
+	l.records = l.records[1:]
+	return record, nil
+}
+
+// ImportTags applies records to the taxonomy of the current tenant as they
+// are read. Records are validated like CreateTags requests; invalid ones and
+// repeated names are reported and ignored. Writes are not transactional, so
+// a failure, including an unreadable record, leaves the records before it
+// applied
+func (s *TagsService) ImportTags(ctx context.Context, records TagRecords, opts ImportOptions) (_ *ImportReport, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TagsService.ImportTags")
+	span.SetAttributes(
+		attribute.String("import.strategy", string(opts.Strategy)),
+		attribute.Bool("import.dry_run", opts.DryRun),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	if opts.Strategy == "" {
+		opts.Strategy = ImportSkip
+	}
+
+	report := &ImportReport{Strategy: opts.Strategy, DryRun: opts.DryRun}
+	seen := make(map[string]bool)
+
+	read := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		record, err := records.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return report, err
+		}
+		read++
+		result := ImportResult{Line: read, Name: record.Name}
+
+		if err := utils.ValidateStruct(&dto.TagsRequest{Name: record.Name}); err != nil {
+			result.Action, result.Err = ActionInvalid, err
+			report.Results = append(report.Results, result)
+			continue
+		}
+		key := repository.FoldName(record.Name)
+		if seen[key] {
+			result.Action, result.Err = ActionInvalid, ErrTagDuplicatedInFile
+			report.Results = append(report.Results, result)
+			continue
+		}
+		seen[key] = true
+
+		existing, err := s.tagsRepo.FindOne(ctx, repository.TagsFilter{Name: record.Name})
+		if err != nil {
+			return report, err
+		}
+		result.Action, err = s.importRecord(ctx, record, existing, opts)
+		if err != nil {
+			return report, err
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	span.SetAttributes(attribute.Int("import.records", read))
+
+	if opts.Strategy == ImportOverwrite {
+		unseen, err := s.unseenTags(ctx, seen)
+		if err != nil {
+			return report, err
+		}
+		for _, tag := range unseen {
+			if !opts.DryRun {
+				if err := s.tagsRepo.Delete(ctx, tag.ID); err != nil {
+					return report, err
+				}
+			}
+			report.Results = append(report.Results, ImportResult{Name: tag.Name, Action: ActionDeleted})
+		}
+	}
+
+	logger.FromContext(ctx).Info("Tags imported",
+		"strategy", opts.Strategy,
+		"dry_run", opts.DryRun,
+		"created", report.Count(ActionCreated),
+		"updated", report.Count(ActionUpdated),
+		"deleted", report.Count(ActionDeleted),
+		"invalid", report.Count(ActionInvalid),
+	)
+	return report, nil
+}
+
+// unseenTags lists the tags missing from the file. They are deleted once the
+// cursor is closed, as SQLite cannot write while it is open
+func (s *TagsService) unseenTags(ctx context.Context, seen map[string]bool) (_ []model.Tags, err error) {
+	cursor, err := s.tagsRepo.Stream(ctx, repository.TagsFilter{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := cursor.Close(ctx); err == nil {
+			err = closeErr
+		}
+	}()
+
+	var unseen []model.Tags
+	for cursor.Next(ctx) {
+		var tag model.Tags
+		if err := cursor.Decode(&tag); err != nil {
+			return nil, err
+		}
+		if !seen[repository.FoldName(tag.Name)] {
+			unseen = append(unseen, tag)
+		}
+	}
+	return unseen, cursor.Err()
+}
+
+func (s *TagsService) importRecord(ctx context.Context, record dto.TagRecord, existing *model.Tags, opts ImportOptions) (ImportAction, error) {
+	if existing == nil {
+		if opts.DryRun {
+			return ActionCreated, nil
+		}
+
+		now := time.Now()
+		tag := &model.Tags{Name: record.Name, CreatedAt: now, UpdatedAt: now}
+		if record.CreatedAt != nil {
+			tag.CreatedAt = *record.CreatedAt
+		}
+		if err := s.tagsRepo.Create(ctx, tag); err != nil {
+			return "", err
+		}
+		return ActionCreated, nil
+	}
+
+	if opts.Strategy == ImportSkip {
+		return ActionSkipped, nil
+	}
+
+	// The name matches ignoring case, so a file can only change its case and
+	// created_at. CSV exports carry whole seconds
+	renamed := record.Name != existing.Name
+	redated := record.CreatedAt != nil && !record.CreatedAt.Truncate(time.Second).Equal(existing.CreatedAt.Truncate(time.Second))
+	if !renamed && !redated {
+		return ActionUnchanged, nil
+	}
+	if opts.DryRun {
+		return ActionUpdated, nil
+	}
+
+	updated := *existing
+	updated.Name = record.Name
+	if redated {
+		updated.CreatedAt = *record.CreatedAt
+	}
+	if err := s.tagsRepo.Update(ctx, &updated); err != nil {
+		return "", err
+	}
+	return ActionUpdated, nil
+}
@@ -0,0 +1,337 @@
+// Package tagfile reads and writes tag taxonomies as JSON, NDJSON or CSV,
+// the formats of the export and import endpoints and of the admin CLI.
+package tagfile
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"time"
+
+	"pre-test-gallery-service/internal/model"
+	"pre-test-gallery-service/pkg/dto"
+)
+
+const (
+	JSON   = "json"
+	NDJSON = "ndjson"
+	CSV    = "csv"
+)
+
+// Formats lists the supported formats
+var Formats = []string{JSON, NDJSON, CSV}
+
+var contentTypes = map[string]string{
+	JSON:   "application/json",
+	NDJSON: "application/x-ndjson",
+	CSV:    "text/csv",
+}
+
+// ContentType returns the media type of format
+func ContentType(format string) string {
+	if format == CSV {
+		return contentTypes[CSV] + "; charset=utf-8"
+	}
+	return contentTypes[format]
+}
+
+// FormatFromContentType returns the format of a request body, or "" when
+// the media type is not one of ours
+func FormatFromContentType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	switch mediaType {
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return NDJSON
+	}
+	for format, ct := range contentTypes {
+		if ct == mediaType {
+			return format
+		}
+	}
+	return ""
+}
+
+// Encoder writes tags one at a time, so an export never holds the whole
+// taxonomy in memory
+type Encoder struct {
+	format string
+	w      io.Writer
+	csv    *csv.Writer
+	json   *json.Encoder
+	count  int
+}
+
+func NewEncoder(w io.Writer, format string) (*Encoder, error) {
+	e := &Encoder{format: format, w: w}
+	switch format {
+	case JSON:
+	case NDJSON:
+		e.json = json.NewEncoder(w)
+	case CSV:
+		e.csv = csv.NewWriter(w)
+		if err := e.csv.Write([]string{"name", "created_at"}); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	return e, nil
+}
+
+func (e *Encoder) Encode(tag model.Tags) error {
+	defer func() { e.count++ }()
+
+	createdAt := tag.CreatedAt.UTC()
+	record := dto.TagRecord{Name: tag.Name, CreatedAt: &createdAt}
+
+	switch e.format {
+	case CSV:
+		return e.csv.Write([]string{tag.Name, createdAt.Format(time.RFC3339)})
+	case NDJSON:
+		return e.json.Encode(record)
+	}
+
+	// A JSON array written element by element, one per line
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	sep := ",\n"
+	if e.count == 0 {
+		sep = "[\n"
+	}
+	if _, err := io.WriteString(e.w, sep); err != nil {
+		return err
+	}
+	_, err = e.w.Write(b)
+	return err
+}
+
+// Close finishes the document; it does not close the underlying writer
+func (e *Encoder) Close() error {
+	switch e.format {
+	case CSV:
+		e.csv.Flush()
+		return e.csv.Error()
+	case JSON:
+		end := "\n]\n"
+		if e.count == 0 {
+			end = "[]\n"
+		}
+		_, err := io.WriteString(e.w, end)
+		return err
+	}
+	return nil
+}
+
+// DecodeError is returned for every failure to read a file, so callers can
+// tell a malformed file from what they do with its records
+type DecodeError struct {
+	Err error
+}
+
+func (e *DecodeError) Error() string { return e.Err.Error() }
+
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+// Decoder reads records one at a time, so an import never holds the whole
+// file in memory. CSV files need a header with a name column; created_at is
+// optional in every format and other fields are ignored
+type Decoder struct {
+	format  string
+	json    *json.Decoder
+	lines   *bufio.Scanner
+	csv     *csv.Reader
+	started bool
+	done    bool
+	// count is the number of records read, line the NDJSON line
+	count int
+	line  int
+	// Columns of the CSV header
+	nameColumn    int
+	createdColumn int
+}
+
+func NewDecoder(r io.Reader, format string) (*Decoder, error) {
+	d := &Decoder{format: format}
+	switch format {
+	case JSON:
+		d.json = json.NewDecoder(r)
+	case NDJSON:
+		d.lines = bufio.NewScanner(r)
+	case CSV:
+		d.csv = csv.NewReader(r)
+		d.csv.FieldsPerRecord = -1
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	return d, nil
+}
+
+// Next returns the next record, or io.EOF after the last one. Other errors
+// are a *DecodeError
+func (d *Decoder) Next() (dto.TagRecord, error) {
+	if d.done {
+		return dto.TagRecord{}, io.EOF
+	}
+
+	var record dto.TagRecord
+	var err error
+	switch d.format {
+	case JSON:
+		record, err = d.nextJSON()
+	case NDJSON:
+		record, err = d.nextNDJSON()
+	default:
+		record, err = d.nextCSV()
+	}
+
+	if errors.Is(err, io.EOF) {
+		d.done = true
+		return dto.TagRecord{}, io.EOF
+	}
+	if err != nil {
+		d.done = true
+		return dto.TagRecord{}, &DecodeError{Err: err}
+	}
+	d.count++
+	return record, nil
+}
+
+// nextJSON reads the elements of an array with the brackets as tokens
+func (d *Decoder) nextJSON() (dto.TagRecord, error) {
+	if !d.started {
+		d.started = true
+		token, err := d.json.Token()
+		if err != nil {
+			return dto.TagRecord{}, fmt.Errorf("read json: %w", unexpectedEOF(err))
+		}
+		if token != json.Delim('[') {
+			return dto.TagRecord{}, errors.New("read json: want an array of records")
+		}
+	}
+
+	if !d.json.More() {
+		if _, err := d.json.Token(); err != nil {
+			return dto.TagRecord{}, fmt.Errorf("read json: %w", unexpectedEOF(err))
+		}
+		return dto.TagRecord{}, io.EOF
+	}
+
+	var record dto.TagRecord
+	if err := d.json.Decode(&record); err != nil {
+		return dto.TagRecord{}, fmt.Errorf("read json record %d: %w", d.count+1, unexpectedEOF(err))
+	}
+	return record, nil
+}
+
+func (d *Decoder) nextNDJSON() (dto.TagRecord, error) {
+	for d.lines.Scan() {
+		d.line++
+		if strings.TrimSpace(d.lines.Text()) == "" {
+			continue
+		}
+		var record dto.TagRecord
+		if err := json.Unmarshal(d.lines.Bytes(), &record); err != nil {
+			return dto.TagRecord{}, fmt.Errorf("read ndjson line %d: %w", d.line, err)
+		}
+		return record, nil
+	}
+	if err := d.lines.Err(); err != nil {
+		return dto.TagRecord{}, fmt.Errorf("read ndjson: %w", err)
+	}
+	return dto.TagRecord{}, io.EOF
+}
+
+func (d *Decoder) nextCSV() (dto.TagRecord, error) {
+	if !d.started {
+		d.started = true
+		if err := d.readHeader(); err != nil {
+			return dto.TagRecord{}, err
+		}
+	}
+
+	row, err := d.csv.Read()
+	if errors.Is(err, io.EOF) {
+		return dto.TagRecord{}, io.EOF
+	}
+	if err != nil {
+		return dto.TagRecord{}, fmt.Errorf("read csv: %w", err)
+	}
+
+	var record dto.TagRecord
+	if d.nameColumn < len(row) {
+		record.Name = row[d.nameColumn]
+	}
+	if d.createdColumn >= 0 && d.createdColumn < len(row) && row[d.createdColumn] != "" {
+		createdAt, err := time.Parse(time.RFC3339, row[d.createdColumn])
+		if err != nil {
+			line, _ := d.csv.FieldPos(0)
+			return dto.TagRecord{}, fmt.Errorf("read csv line %d: created_at: %w", line, err)
+		}
+		record.CreatedAt = &createdAt
+	}
+	return record, nil
+}
+
+// readHeader finds the columns; an empty file has no records
+func (d *Decoder) readHeader() error {
+	header, err := d.csv.Read()
+	if errors.Is(err, io.EOF) {
+		return io.EOF
+	}
+	if err != nil {
+		return fmt.Errorf("read csv: %w", err)
+	}
+
+	// Spreadsheet exports often start with a UTF-8 byte order mark
+	d.nameColumn, d.createdColumn = -1, -1
+	for i, column := range header {
+		switch strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))) {
+		case "name":
+			d.nameColumn = i
+		case "created_at":
+			d.createdColumn = i
+		}
+	}
+	if d.nameColumn < 0 {
+		return errors.New("read csv: no name column in the header")
+	}
+	return nil
+}
+
+// unexpectedEOF keeps a truncated document from reading as its end
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Decode reads every record of a file
+func Decode(r io.Reader, format string) ([]dto.TagRecord, error) {
+	d, err := NewDecoder(r, format)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []dto.TagRecord
+	for {
+		record, err := d.Next()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+}
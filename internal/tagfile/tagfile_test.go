@@ -0,0 +1,128 @@
+package tagfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"pre-test-gallery-service/internal/model"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tags := []model.Tags{
+		{Name: "landscape", CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{Name: "ทะเล, \"ภูเขา\"", CreatedAt: time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC)},
+	}
+
+	for _, format := range Formats {
+		var buf bytes.Buffer
+		encoder, err := NewEncoder(&buf, format)
+		if err != nil {
+			t.Fatalf("%s: NewEncoder: %v", format, err)
+		}
+		for _, tag := range tags {
+			if err := encoder.Encode(tag); err != nil {
+				t.Fatalf("%s: Encode: %v", format, err)
+			}
+		}
+		if err := encoder.Close(); err != nil {
+			t.Fatalf("%s: Close: %v", format, err)
+		}
+
+		if format == JSON && !json.Valid(buf.Bytes()) {
+			t.Fatalf("json export is not valid JSON: %s", buf.String())
+		}
+
+		records, err := Decode(&buf, format)
+		if err != nil {
+			t.Fatalf("%s: Decode: %v", format, err)
+		}
+		if len(records) != len(tags) {
+			t.Fatalf("%s: decoded %d records, want %d", format, len(records), len(tags))
+		}
+		for i, record := range records {
+			if record.Name != tags[i].Name || record.CreatedAt == nil || !record.CreatedAt.Equal(tags[i].CreatedAt) {
+				t.Errorf("%s: records[%d] = %q %v, want %q %v", format, i, record.Name, record.CreatedAt, tags[i].Name, tags[i].CreatedAt)
+			}
+		}
+	}
+}
+
+func TestEmptyJSONExportIsAnArray(t *testing.T) {
+	var buf bytes.Buffer
+	encoder, _ := NewEncoder(&buf, JSON)
+	if err := encoder.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if got := strings.TrimSpace(buf.String()); got != "[]" {
+		t.Fatalf("empty export = %q, want []", got)
+	}
+}
+
+func TestDecodeCSV(t *testing.T) {
+	records, err := Decode(strings.NewReader("\ufeffid,Name\n1,street\n2,night\n"), CSV)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(records) != 2 || records[0].Name != "street" || records[1].Name != "night" || records[0].CreatedAt != nil {
+		t.Fatalf("records = %+v", records)
+	}
+
+	if _, err := Decode(strings.NewReader("id,label\n1,street\n"), CSV); err == nil {
+		t.Fatal("Decode accepted a CSV file without a name column")
+	}
+	if _, err := Decode(strings.NewReader("name,created_at\nstreet,yesterday\n"), CSV); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("Decode error = %v, want the line of the bad created_at", err)
+	}
+}
+
+func TestDecoderReadsOneRecordAtATime(t *testing.T) {
+	tests := []struct {
+		format string
+		input  string
+	}{
+		{format: JSON, input: `[{"name":"street"},{"name":`},
+		{format: JSON, input: `[{"name":"street"}`},
+		{format: NDJSON, input: "{\"name\":\"street\"}\n{\"name\"\n"},
+		{format: CSV, input: "name,created_at\nstreet,\nnight,yesterday\n"},
+	}
+
+	for _, tt := range tests {
+		d, err := NewDecoder(strings.NewReader(tt.input), tt.format)
+		if err != nil {
+			t.Fatalf("NewDecoder: %v", err)
+		}
+		// The record before the damage is returned before the error
+		if record, err := d.Next(); err != nil || record.Name != "street" {
+			t.Fatalf("%s %q: first Next = %+v, %v", tt.format, tt.input, record, err)
+		}
+		_, err = d.Next()
+		var decodeErr *DecodeError
+		if !errors.As(err, &decodeErr) || errors.Is(err, io.EOF) {
+			t.Errorf("%s %q: second Next error = %v, want a DecodeError", tt.format, tt.input, err)
+		}
+		if _, err := d.Next(); !errors.Is(err, io.EOF) {
+			t.Errorf("%s %q: Next after an error = %v, want io.EOF", tt.format, tt.input, err)
+		}
+	}
+}
+
+func TestFormatFromContentType(t *testing.T) {
+	tests := map[string]string{
+		"application/json; charset=utf-8": JSON,
+		"application/x-ndjson":            NDJSON,
+		"application/jsonl":               NDJSON,
+		"text/csv":                        CSV,
+		"text/plain":                      "",
+		"":                                "",
+	}
+	for contentType, want := range tests {
+		if got := FormatFromContentType(contentType); got != want {
+			t.Errorf("FormatFromContentType(%q) = %q, want %q", contentType, got, want)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// Package apperror defines the typed errors returned by services and
+// middleware. Each error carries a Kind, which decides the HTTP status in one
+// place, and a stable machine-readable Code that clients can switch on.
+package apperror
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+type Kind uint8
+
+const (
+	KindInternal Kind = iota
+	KindInvalid
+	KindUnauthorized
+	KindForbidden
+	KindNotFound
+	KindConflict
+	KindTooLarge
+	KindRateLimited
+	KindTimeout
+	KindCancelled
+	KindUnavailable
+)
+
+// StatusClientClosedRequest is the nginx convention for requests abandoned
+// before a response was written
+const StatusClientClosedRequest = 499
+
+// Generic codes; domain specific codes are declared next to their errors
+const (
+	CodeBadRequest         = "BAD_REQUEST"
+	CodeInvalidRequestBody = "INVALID_REQUEST_BODY"
+	CodeValidationFailed   = "VALIDATION_FAILED"
+	CodeUnauthorized       = "UNAUTHORIZED"
+	CodeForbidden          = "FORBIDDEN"
+	CodeNotFound           = "NOT_FOUND"
+	CodeMethodNotAllowed   = "METHOD_NOT_ALLOWED"
+	CodeConflict           = "CONFLICT"
+	CodePayloadTooLarge    = "PAYLOAD_TOO_LARGE"
+	CodeRateLimited        = "RATE_LIMITED"
+	CodeRequestTimeout     = "REQUEST_TIMEOUT"
+	CodeRequestCancelled   = "REQUEST_CANCELLED"
+	CodeServiceUnavailable = "SERVICE_UNAVAILABLE"
+	CodeInternal           = "INTERNAL_ERROR"
+)
+
+var (
+	ErrInvalidRequestBody = New(KindInvalid, CodeInvalidRequestBody, "Invalid request body")
+	ErrRateLimited        = New(KindRateLimited, CodeRateLimited, "Rate limit exceeded. Please try again later.")
+	ErrRequestTimeout     = New(KindTimeout, CodeRequestTimeout, "Request timed out")
+	ErrRequestCancelled   = New(KindCancelled, CodeRequestCancelled, "Request cancelled")
+	ErrInternal           = New(KindInternal, CodeInternal, "Internal server error")
+)
+
+// FieldError describes one invalid field of a request body
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []FieldError
+	// Err is the underlying cause; SendError logs it for 5xx, 401 and 403
+	// responses and never sends it to clients
+	Err error
+	// status overrides the status derived from Kind, see FromStatus
+	status int
+}
+
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// Validation reports a request body that failed validation
+func Validation(fields []FieldError) *Error {
+	return &Error{Kind: KindInvalid, Code: CodeValidationFailed, Message: "Request validation failed", Fields: fields}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Code + ": " + e.Message + ": " + e.Err.Error()
+	}
+	return e.Code + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches any *Error with the same code, so errors.Is works against the
+// package level sentinels even after Wrap or WithMessage
+func (e *Error) Is(target error) bool {
+	var t *Error
+	return errors.As(target, &t) && t.Code == e.Code
+}
+
+// Wrap returns a copy of e that records cause
+func (e *Error) Wrap(cause error) *Error {
+	clone := *e
+	clone.Err = cause
+	return &clone
+}
+
+// WithMessage returns a copy of e with a more specific client message
+func (e *Error) WithMessage(message string) *Error {
+	clone := *e
+	clone.Message = message
+	return &clone
+}
+
+// Status maps the kind to its HTTP status code
+func (e *Error) Status() int {
+	if e.status != 0 {
+		return e.status
+	}
+
+	switch e.Kind {
+	case KindInvalid:
+		return http.StatusBadRequest
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	case KindForbidden:
+		return http.StatusForbidden
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindConflict:
+		return http.StatusConflict
+	case KindTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case KindRateLimited:
+		return http.StatusTooManyRequests
+	case KindTimeout:
+		return http.StatusGatewayTimeout
+	case KindCancelled:
+		return StatusClientClosedRequest
+	case KindUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// From converts any error into an *Error. Context errors become timeouts or
+// cancellations, and unknown errors become ErrInternal wrapping the cause so
+// their text is not leaked to clients
+func From(err error) *Error {
+	var appErr *Error
+	switch {
+	case errors.As(err, &appErr):
+		return appErr
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrRequestTimeout.Wrap(err)
+	case errors.Is(err, context.Canceled):
+		return ErrRequestCancelled.Wrap(err)
+	default:
+		return ErrInternal.Wrap(err)
+	}
+}
+
+// FromStatus builds an error for a bare HTTP status, such as the ones raised
+// by the router for unknown routes or oversized bodies; the status is kept
+// as is and only the kind and code are inferred
+func FromStatus(status int, message string) *Error {
+	kind, code := KindInternal, CodeInternal
+	switch status {
+	case http.StatusBadRequest:
+		kind, code = KindInvalid, CodeBadRequest
+	case http.StatusUnauthorized:
+		kind, code = KindUnauthorized, CodeUnauthorized
+	case http.StatusForbidden:
+		kind, code = KindForbidden, CodeForbidden
+	case http.StatusNotFound:
+		kind, code = KindNotFound, CodeNotFound
+	case http.StatusMethodNotAllowed:
+		kind, code = KindInvalid, CodeMethodNotAllowed
+	case http.StatusConflict:
+		kind, code = KindConflict, CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		kind, code = KindTooLarge, CodePayloadTooLarge
+	case http.StatusTooManyRequests:
+		kind, code = KindRateLimited, CodeRateLimited
+	case http.StatusServiceUnavailable:
+		kind, code = KindUnavailable, CodeServiceUnavailable
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		kind, code = KindTimeout, CodeRequestTimeout
+	default:
+		if status >= 400 && status < 500 {
+			kind, code = KindInvalid, CodeBadRequest
+		}
+	}
+	return &Error{Kind: kind, Code: code, Message: message, status: status}
+}
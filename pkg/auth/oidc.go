@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrMissingIDToken = errors.New("id_token missing from token response")
+	ErrInvalidNonce   = errors.New("id_token nonce does not match")
+)
+
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	RoleMapper   RoleMapper
+	// TenantClaim names the ID token claim carrying the user's workspace
+	TenantClaim string
+}
+
+// Identity is the authenticated user resolved from a verified ID token
+type Identity struct {
+	Subject string
+	Email   string
+	Name    string
+	Role    string
+	Tenant  string
+}
+
+type OIDCProvider struct {
+	oauth2Config oauth2.Config
+	verifier     *oidc.IDTokenVerifier
+	roleMapper   RoleMapper
+	tenantClaim  string
+}
+
+// NewOIDCProvider runs discovery against the issuer and prepares the
+// authorization-code flow and ID token verifier (JWKS is fetched lazily)
+func NewOIDCProvider(ctx context.Context, cfg OIDCConfig) (*OIDCProvider, error) {
+	provider, err := oidc.NewProvider(ctx, cfg.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"profile", "email"}
+	}
+
+	return &OIDCProvider{
+		oauth2Config: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       append([]string{oidc.ScopeOpenID}, scopes...),
+		},
+		verifier:    provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		roleMapper:  cfg.RoleMapper,
+		tenantClaim: cfg.TenantClaim,
+	}, nil
+}
+
+func (p *OIDCProvider) AuthCodeURL(state, nonce string) string {
+	return p.oauth2Config.AuthCodeURL(state, oidc.Nonce(nonce))
+}
+
+// Exchange trades the authorization code for tokens and verifies the ID token
+// signature, issuer, audience, expiry and nonce
+func (p *OIDCProvider) Exchange(ctx context.Context, code, nonce string) (*Identity, error) {
+	token, err := p.oauth2Config.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("oidc code exchange: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, ErrMissingIDToken
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("oidc verify id_token: %w", err)
+	}
+	if nonce == "" || idToken.Nonce != nonce {
+		return nil, ErrInvalidNonce
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{
+		Subject: idToken.Subject,
+		Role:    p.roleMapper.MapRole(claims),
+	}
+	identity.Email, _ = claims["email"].(string)
+	identity.Name, _ = claims["name"].(string)
+	if p.tenantClaim != "" {
+		identity.Tenant, _ = claims[p.tenantClaim].(string)
+	}
+
+	return identity, nil
+}
+
+// RandomString returns a URL-safe random value for OAuth2 state and nonce
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
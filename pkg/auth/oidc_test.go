@@ -0,0 +1,181 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"pre-test-gallery-service/pkg/auth"
+	"pre-test-gallery-service/pkg/auth/oidctest"
+)
+
+func newTestProvider(t *testing.T, idp *oidctest.IdP) *auth.OIDCProvider {
+	t.Helper()
+
+	provider, err := auth.NewOIDCProvider(context.Background(), auth.OIDCConfig{
+		IssuerURL:    idp.Issuer(),
+		ClientID:     idp.ClientID,
+		ClientSecret: idp.ClientSecret,
+		RedirectURL:  "http://gallery.test/api/v1/auth/oidc/callback",
+		RoleMapper: auth.RoleMapper{
+			Claim:       "groups",
+			Mapping:     map[string]string{"gallery-admins": auth.RoleAdmin, "gallery-editors": auth.RoleEditor},
+			DefaultRole: auth.RoleViewer,
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewOIDCProvider: %v", err)
+	}
+	return provider
+}
+
+func newTestIdP(t *testing.T) *oidctest.IdP {
+	t.Helper()
+
+	idp, err := oidctest.NewIdP("gallery", "secret")
+	if err != nil {
+		t.Fatalf("NewIdP: %v", err)
+	}
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+func login(t *testing.T, idp *oidctest.IdP, provider *auth.OIDCProvider, nonce string) (*url.URL, string) {
+	t.Helper()
+
+	redirect, err := idp.Authorize(provider.AuthCodeURL("state-1", nonce))
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if got := redirect.Query().Get("state"); got != "state-1" {
+		t.Fatalf("state = %q, want %q", got, "state-1")
+	}
+	return redirect, redirect.Query().Get("code")
+}
+
+func TestOIDCProviderExchange(t *testing.T) {
+	tests := []struct {
+		name     string
+		groups   []interface{}
+		wantRole string
+	}{
+		{name: "no groups", groups: nil, wantRole: auth.RoleViewer},
+		{name: "editor", groups: []interface{}{"staff", "gallery-editors"}, wantRole: auth.RoleEditor},
+		{name: "highest role wins", groups: []interface{}{"gallery-editors", "gallery-admins"}, wantRole: auth.RoleAdmin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newTestIdP(t)
+			idp.Claims = map[string]interface{}{
+				"sub":    "user-42",
+				"email":  "user@example.com",
+				"name":   "Test User",
+				"groups": tt.groups,
+			}
+			provider := newTestProvider(t, idp)
+
+			_, code := login(t, idp, provider, "nonce-1")
+			identity, err := provider.Exchange(context.Background(), code, "nonce-1")
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+
+			if identity.Subject != "user-42" || identity.Email != "user@example.com" || identity.Name != "Test User" {
+				t.Errorf("unexpected identity %+v", identity)
+			}
+			if identity.Role != tt.wantRole {
+				t.Errorf("role = %q, want %q", identity.Role, tt.wantRole)
+			}
+		})
+	}
+}
+
+func TestOIDCProviderExchangeRejectsNonceMismatch(t *testing.T) {
+	idp := newTestIdP(t)
+	provider := newTestProvider(t, idp)
+
+	_, code := login(t, idp, provider, "nonce-1")
+	if _, err := provider.Exchange(context.Background(), code, "other-nonce"); !errors.Is(err, auth.ErrInvalidNonce) {
+		t.Fatalf("err = %v, want %v", err, auth.ErrInvalidNonce)
+	}
+}
+
+func TestOIDCProviderExchangeRejectsEmptyNonce(t *testing.T) {
+	idp := newTestIdP(t)
+	provider := newTestProvider(t, idp)
+
+	// The ID token carries no nonce either, so they would compare equal
+	_, code := login(t, idp, provider, "")
+	if _, err := provider.Exchange(context.Background(), code, ""); !errors.Is(err, auth.ErrInvalidNonce) {
+		t.Fatalf("err = %v, want %v", err, auth.ErrInvalidNonce)
+	}
+}
+
+func TestOIDCProviderExchangeRejectsUnknownCode(t *testing.T) {
+	idp := newTestIdP(t)
+	provider := newTestProvider(t, idp)
+
+	if _, err := provider.Exchange(context.Background(), "bogus", "nonce-1"); err == nil {
+		t.Fatal("expected error for unknown code")
+	}
+}
+
+func TestOIDCProviderRejectsForeignAudience(t *testing.T) {
+	idp := newTestIdP(t)
+	provider := newTestProvider(t, idp)
+
+	// ID tokens minted for another client must fail audience verification
+	idp.Audience = "other-client"
+	_, code := login(t, idp, provider, "nonce-1")
+	if _, err := provider.Exchange(context.Background(), code, "nonce-1"); err == nil {
+		t.Fatal("expected error for foreign audience")
+	}
+}
+
+func TestParseRoleMapping(t *testing.T) {
+	mapping, err := auth.ParseRoleMapping(" admins=admin, editors = editor ,")
+	if err != nil {
+		t.Fatalf("ParseRoleMapping: %v", err)
+	}
+	if mapping["admins"] != auth.RoleAdmin || mapping["editors"] != auth.RoleEditor || len(mapping) != 2 {
+		t.Fatalf("unexpected mapping %v", mapping)
+	}
+
+	for _, raw := range []string{"admins", "admins=root", "=admin"} {
+		if _, err := auth.ParseRoleMapping(raw); err == nil {
+			t.Errorf("ParseRoleMapping(%q) expected error", raw)
+		}
+	}
+}
+
+func TestTokenIssuer(t *testing.T) {
+	issuer := auth.NewTokenIssuer("secret", time.Hour)
+
+	token, expiresAt, err := issuer.Issue(&auth.Identity{Subject: "user-1", Role: auth.RoleEditor})
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if time.Until(expiresAt) <= 0 {
+		t.Fatalf("expiresAt %v is not in the future", expiresAt)
+	}
+
+	claims, err := issuer.Parse(token)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if claims.Subject != "user-1" || claims.Role != auth.RoleEditor {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+
+	if _, err := auth.NewTokenIssuer("other", time.Hour).Parse(token); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Fatalf("err = %v, want %v", err, auth.ErrInvalidToken)
+	}
+
+	expired, _, _ := auth.NewTokenIssuer("secret", -time.Minute).Issue(&auth.Identity{Subject: "user-1"})
+	if _, err := issuer.Parse(expired); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Fatalf("expired token err = %v, want %v", err, auth.ErrInvalidToken)
+	}
+}
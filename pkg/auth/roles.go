@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// rolePriority orders roles so that the most privileged mapped role wins
+var rolePriority = map[string]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+func IsValidRole(role string) bool {
+	_, ok := rolePriority[role]
+	return ok
+}
+
+// RoleMapper maps values of an ID token claim (e.g. "groups") to gallery roles
+type RoleMapper struct {
+	Claim       string
+	Mapping     map[string]string
+	DefaultRole string
+}
+
+// ParseRoleMapping parses "group=role,group2=role2" into a mapping
+func ParseRoleMapping(raw string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		value, role, ok := strings.Cut(pair, "=")
+		value, role = strings.TrimSpace(value), strings.TrimSpace(role)
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid role mapping %q", pair)
+		}
+		if !IsValidRole(role) {
+			return nil, fmt.Errorf("invalid role %q in role mapping", role)
+		}
+		mapping[value] = role
+	}
+	return mapping, nil
+}
+
+// MapRole returns the most privileged role matched by the configured claim,
+// falling back to the default role when nothing matches
+func (m *RoleMapper) MapRole(claims map[string]interface{}) string {
+	role := m.DefaultRole
+	for _, value := range claimValues(claims[m.Claim]) {
+		mapped, ok := m.Mapping[value]
+		if ok && rolePriority[mapped] > rolePriority[role] {
+			role = mapped
+		}
+	}
+	return role
+}
+
+func claimValues(claim interface{}) []string {
+	switch v := claim.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	case []string:
+		return v
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// APIKeySubjectPrefix marks the subject of tokens issued for automation with
+// "gallery apikeys issue"
+const APIKeySubjectPrefix = "apikey:"
+
+type Claims struct {
+	Email  string `json:"email,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Role   string `json:"role"`
+	Tenant string `json:"tenant,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// APIKey returns the key name when the token was issued as an API key
+func (c *Claims) APIKey() (string, bool) {
+	name, ok := strings.CutPrefix(c.Subject, APIKeySubjectPrefix)
+	return name, ok && name != ""
+}
+
+// TokenIssuer signs and parses the gallery's own access tokens, which are
+// handed out after a successful OIDC login
+type TokenIssuer struct {
+	secret    []byte
+	expiresIn time.Duration
+}
+
+func NewTokenIssuer(secret string, expiresIn time.Duration) *TokenIssuer {
+	return &TokenIssuer{
+		secret:    []byte(secret),
+		expiresIn: expiresIn,
+	}
+}
+
+func (t *TokenIssuer) Issue(identity *Identity) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(t.expiresIn)
+
+	claims := Claims{
+		Email:  identity.Email,
+		Name:   identity.Name,
+		Role:   identity.Role,
+		Tenant: identity.Tenant,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   identity.Subject,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(t.secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+func (t *TokenIssuer) Parse(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return t.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
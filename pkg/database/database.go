@@ -2,29 +2,234 @@ package database
 
 import (
 	"context"
-	"log"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 )
 
-func ConnectMongoDB(uri string) (*mongo.Client, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+const (
+	// connectBackoff is the first wait between connection attempts; it
+	// doubles up to maxConnectBackoff
+	connectBackoff    = 500 * time.Millisecond
+	maxConnectBackoff = 10 * time.Second
+	// defaultPingTimeout bounds an attempt when ConnectTimeout is not set
+	defaultPingTimeout = 10 * time.Second
+)
 
-	clientOptions := options.Client().ApplyURI(uri)
-	client, err := mongo.Connect(ctx, clientOptions)
+// compressors are the wire compressors the driver supports
+var compressors = []string{"snappy", "zlib", "zstd"}
+
+// MongoOptions tunes the client on top of the URI. Zero values keep what the
+// URI or the driver sets, so only the settings an operator chose override it
+type MongoOptions struct {
+	URI string
+
+	MaxPoolSize     int
+	MinPoolSize     int
+	MaxConnIdleTime time.Duration
+
+	ConnectTimeout         time.Duration
+	ServerSelectionTimeout time.Duration
+	// Timeout bounds every operation on the client side
+	Timeout time.Duration
+
+	// ReadPreference is a mode such as primary or secondaryPreferred
+	ReadPreference string
+	// WriteConcern is majority or the number of nodes that acknowledge
+	WriteConcern string
+	// DisableRetryWrites and DisableRetryReads turn off the driver default
+	DisableRetryWrites bool
+	DisableRetryReads  bool
+	// Compressors lists snappy, zlib or zstd in order of preference
+	Compressors []string
+
+	TLS bool
+	// TLSCAFile verifies the server with a private CA; TLSCertFile and
+	// TLSKeyFile authenticate the client with X.509
+	TLSCAFile   string
+	TLSCertFile string
+	TLSKeyFile  string
+	// TLSInsecure skips server certificate verification, for development
+	TLSInsecure bool
+
+	// ConnectRetryTimeout is how long ConnectMongoDB keeps retrying a server
+	// that is not up yet; zero tries once
+	ConnectRetryTimeout time.Duration
+}
+
+// ClientOptions builds the driver options, rejecting invalid values before
+// anything connects
+func (o MongoOptions) ClientOptions() (*options.ClientOptions, error) {
+	opts := options.Client().
+		ApplyURI(o.URI).
+		SetMonitor(NewCommandMonitor()).
+		SetPoolMonitor(NewPoolMonitor(slog.Default()))
+
+	if o.MaxPoolSize > 0 {
+		opts.SetMaxPoolSize(uint64(o.MaxPoolSize))
+	}
+	if o.MinPoolSize > 0 {
+		opts.SetMinPoolSize(uint64(o.MinPoolSize))
+	}
+	if o.MaxConnIdleTime > 0 {
+		opts.SetMaxConnIdleTime(o.MaxConnIdleTime)
+	}
+	if o.ConnectTimeout > 0 {
+		opts.SetConnectTimeout(o.ConnectTimeout)
+	}
+	if o.ServerSelectionTimeout > 0 {
+		opts.SetServerSelectionTimeout(o.ServerSelectionTimeout)
+	}
+	if o.Timeout > 0 {
+		opts.SetTimeout(o.Timeout)
+	}
+
+	if o.ReadPreference != "" {
+		mode, err := readpref.ModeFromString(o.ReadPreference)
+		if err != nil {
+			return nil, err
+		}
+		pref, err := readpref.New(mode)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetReadPreference(pref)
+	}
+
+	if o.WriteConcern != "" {
+		wc, err := parseWriteConcern(o.WriteConcern)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetWriteConcern(wc)
+	}
+
+	if o.DisableRetryWrites {
+		opts.SetRetryWrites(false)
+	}
+	if o.DisableRetryReads {
+		opts.SetRetryReads(false)
+	}
+	if len(o.Compressors) > 0 {
+		// The driver silently skips compressors it does not know
+		for _, c := range o.Compressors {
+			if !slices.Contains(compressors, c) {
+				return nil, fmt.Errorf("unknown compressor %q, want one of %s", c, strings.Join(compressors, ", "))
+			}
+		}
+		opts.SetCompressors(o.Compressors)
+	}
+
+	if o.TLS || o.TLSCAFile != "" || o.TLSCertFile != "" || o.TLSInsecure {
+		tlsConfig, err := o.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	return opts, opts.Validate()
+}
+
+func (o MongoOptions) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: o.TLSInsecure,
+	}
+
+	if o.TLSCAFile != "" {
+		pem, err := os.ReadFile(o.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read MongoDB CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("MongoDB CA file %s holds no PEM certificate", o.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if o.TLSCertFile != "" || o.TLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.TLSCertFile, o.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load MongoDB client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+func parseWriteConcern(value string) (*writeconcern.WriteConcern, error) {
+	if value == "majority" {
+		return writeconcern.Majority(), nil
+	}
+	w, err := strconv.Atoi(value)
+	if err != nil || w < 0 {
+		return nil, fmt.Errorf("write concern must be majority or a number of nodes, got %q", value)
+	}
+	return &writeconcern.WriteConcern{W: w}, nil
+}
+
+// ConnectMongoDB connects and pings the deployment. While ctx allows and
+// ConnectRetryTimeout has not passed, failed pings are retried with
+// exponential backoff, so the service can start before MongoDB is ready
+func ConnectMongoDB(ctx context.Context, o MongoOptions) (*mongo.Client, error) {
+	clientOptions, err := o.ClientOptions()
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("invalid MongoDB options: %w", err)
 	}
 
-	// Ping the database
-	err = client.Ping(ctx, nil)
+	// Connect only validates options and starts background monitoring
+	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Println("Connected to MongoDB!")
-	return client, nil
+	deadline := time.Now().Add(o.ConnectRetryTimeout)
+	backoff := connectBackoff
+	for attempt := 1; ; attempt++ {
+		err = ping(ctx, client, o)
+		if err == nil {
+			slog.Info("Connected to MongoDB", "attempts", attempt)
+			return client, nil
+		}
+
+		if ctx.Err() != nil || time.Now().Add(backoff).After(deadline) {
+			_ = client.Disconnect(context.Background())
+			return nil, fmt.Errorf("ping MongoDB after %d attempt(s): %w", attempt, err)
+		}
+
+		slog.Warn("MongoDB is not reachable yet, retrying", "attempt", attempt, "retry_in", backoff.String(), "error", err)
+		select {
+		case <-ctx.Done():
+			_ = client.Disconnect(context.Background())
+			return nil, errors.Join(ctx.Err(), err)
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxConnectBackoff)
+	}
+}
+
+// ping bounds one attempt by the connect timeout
+func ping(ctx context.Context, client *mongo.Client, o MongoOptions) error {
+	timeout := o.ConnectTimeout
+	if timeout <= 0 {
+		timeout = defaultPingTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return client.Ping(ctx, nil)
 }
@@ -0,0 +1,111 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+func TestClientOptions(t *testing.T) {
+	opts, err := MongoOptions{
+		URI:                "mongodb://localhost:27017/?maxPoolSize=50",
+		MinPoolSize:        2,
+		ReadPreference:     "secondaryPreferred",
+		WriteConcern:       "majority",
+		DisableRetryWrites: true,
+		Compressors:        []string{"zstd", "snappy"},
+	}.ClientOptions()
+	if err != nil {
+		t.Fatalf("ClientOptions: %v", err)
+	}
+
+	if opts.MaxPoolSize == nil || *opts.MaxPoolSize != 50 {
+		t.Errorf("MaxPoolSize = %v, want the URI value 50", opts.MaxPoolSize)
+	}
+	if opts.MinPoolSize == nil || *opts.MinPoolSize != 2 {
+		t.Errorf("MinPoolSize = %v, want 2", opts.MinPoolSize)
+	}
+	if opts.ReadPreference == nil || opts.ReadPreference.Mode() != readpref.SecondaryPreferredMode {
+		t.Errorf("ReadPreference = %v, want secondaryPreferred", opts.ReadPreference)
+	}
+	if opts.WriteConcern == nil || opts.WriteConcern.W != "majority" {
+		t.Errorf("WriteConcern = %v, want majority", opts.WriteConcern)
+	}
+	if opts.RetryWrites == nil || *opts.RetryWrites {
+		t.Errorf("RetryWrites = %v, want false", opts.RetryWrites)
+	}
+	if opts.RetryReads != nil {
+		t.Errorf("RetryReads = %v, want the driver default", *opts.RetryReads)
+	}
+	if strings.Join(opts.Compressors, ",") != "zstd,snappy" {
+		t.Errorf("Compressors = %v", opts.Compressors)
+	}
+	if opts.TLSConfig != nil {
+		t.Error("TLS is enabled without being asked for")
+	}
+	if opts.PoolMonitor == nil || opts.Monitor == nil {
+		t.Error("monitors are not set")
+	}
+}
+
+func TestClientOptionsRejectsInvalidValues(t *testing.T) {
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	tests := map[string]MongoOptions{
+		"read preference": {ReadPreference: "secondaryOnly"},
+		"write concern":   {WriteConcern: "all"},
+		"compressor":      {Compressors: []string{"gzip"}},
+		"missing CA file": {TLS: true, TLSCAFile: filepath.Join(t.TempDir(), "missing.pem")},
+		"CA file":         {TLS: true, TLSCAFile: notPEM},
+		"pool size":       {MaxPoolSize: 1, MinPoolSize: 2},
+	}
+	for name, o := range tests {
+		t.Run(name, func(t *testing.T) {
+			o.URI = "mongodb://localhost:27017"
+			if _, err := o.ClientOptions(); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestConnectMongoDBGivesUp(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for server selection")
+	}
+
+	// Nothing listens on port 1, so every ping fails
+	o := MongoOptions{
+		URI:                    "mongodb://127.0.0.1:1/?directConnection=true",
+		ConnectTimeout:         200 * time.Millisecond,
+		ServerSelectionTimeout: 200 * time.Millisecond,
+		ConnectRetryTimeout:    time.Second,
+	}
+
+	start := time.Now()
+	_, err := ConnectMongoDB(context.Background(), o)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "attempt(s)") {
+		t.Errorf("error %q does not report the attempts", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("gave up after %s, want about ConnectRetryTimeout", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	o.ConnectRetryTimeout = time.Minute
+	if _, err := ConnectMongoDB(ctx, o); err == nil {
+		t.Fatal("expected a canceled context to stop the retries")
+	}
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"log/slog"
+
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// NewPoolMonitor logs connection pool events: pool lifecycle and connection
+// churn at debug, connections lost to errors, failed checkouts and cleared
+// pools at warn. Checkouts and checkins are too frequent to log
+func NewPoolMonitor(log *slog.Logger) *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(evt *event.PoolEvent) {
+			attrs := []any{slog.String("event", evt.Type), slog.String("address", evt.Address)}
+			if evt.ConnectionID != 0 {
+				attrs = append(attrs, slog.Uint64("connection_id", evt.ConnectionID))
+			}
+			if evt.Reason != "" {
+				attrs = append(attrs, slog.String("reason", evt.Reason))
+			}
+			if evt.Error != nil {
+				attrs = append(attrs, slog.Any("error", evt.Error))
+			}
+
+			switch evt.Type {
+			case event.PoolCleared:
+				log.Warn("MongoDB connection pool cleared", attrs...)
+			case event.GetFailed:
+				log.Warn("MongoDB connection checkout failed", append(attrs, slog.Duration("waited", evt.Duration))...)
+			case event.ConnectionClosed:
+				if evt.Reason == event.ReasonError {
+					log.Warn("MongoDB connection closed", attrs...)
+					return
+				}
+				log.Debug("MongoDB connection closed", attrs...)
+			case event.PoolCreated:
+				if evt.PoolOptions != nil {
+					attrs = append(attrs,
+						slog.Uint64("max_pool_size", evt.PoolOptions.MaxPoolSize),
+						slog.Uint64("min_pool_size", evt.PoolOptions.MinPoolSize),
+					)
+				}
+				log.Debug("MongoDB connection pool created", attrs...)
+			case event.PoolReady, event.PoolClosedEvent, event.ConnectionCreated:
+				log.Debug("MongoDB connection pool event", attrs...)
+			}
+		},
+	}
+}
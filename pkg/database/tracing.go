@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"sync"
+
+	"pre-test-gallery-service/pkg/tracing"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// commandTracer opens a client span for every MongoDB command, parented to
+// the span in the context the repository passed to the driver
+type commandTracer struct {
+	spans sync.Map
+}
+
+// NewCommandMonitor returns a monitor that traces MongoDB commands
+func NewCommandMonitor() *event.CommandMonitor {
+	t := &commandTracer{}
+	return &event.CommandMonitor{
+		Started:   t.started,
+		Succeeded: t.succeeded,
+		Failed:    t.failed,
+	}
+}
+
+func (t *commandTracer) started(ctx context.Context, evt *event.CommandStartedEvent) {
+	attrs := []attribute.KeyValue{
+		semconv.DBSystemMongoDB,
+		semconv.DBNamespace(evt.DatabaseName),
+		semconv.DBOperationName(evt.CommandName),
+	}
+	if collection, ok := evt.Command.Lookup(evt.CommandName).StringValueOK(); ok {
+		attrs = append(attrs, semconv.DBCollectionName(collection))
+	}
+
+	_, span := tracing.Tracer().Start(ctx, "mongodb "+evt.CommandName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	t.spans.Store(evt.RequestID, span)
+}
+
+func (t *commandTracer) succeeded(ctx context.Context, evt *event.CommandSucceededEvent) {
+	if span, ok := t.spans.LoadAndDelete(evt.RequestID); ok {
+		span.(trace.Span).End()
+	}
+}
+
+func (t *commandTracer) failed(ctx context.Context, evt *event.CommandFailedEvent) {
+	if span, ok := t.spans.LoadAndDelete(evt.RequestID); ok {
+		span.(trace.Span).SetStatus(codes.Error, evt.Failure)
+		span.(trace.Span).End()
+	}
+}
@@ -0,0 +1,18 @@
+package dto
+
+import "time"
+
+type AuthUser struct {
+	Subject string `json:"subject"`
+	Email   string `json:"email,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Role    string `json:"role"`
+	Tenant  string `json:"tenant,omitempty"`
+}
+
+type AuthResponse struct {
+	AccessToken string    `json:"access_token"`
+	TokenType   string    `json:"token_type"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	User        AuthUser  `json:"user"`
+}
@@ -1,5 +1,66 @@
 package dto
 
+import (
+	"pre-test-gallery-service/pkg/apperror"
+	"time"
+)
+
 type TagsRequest struct {
-	Name string `json:"name" binding:"required"`
+	Name string `json:"name" binding:"required,max=50,tag_name,not_reserved"`
+}
+
+// TagParams names an existing tag; the create rules are not applied, so
+// tags stored before a rule changed can still be found and deleted
+type TagParams struct {
+	Name string `params:"tag" binding:"required"`
+}
+
+// TagListQuery filters GET /tags; times are RFC 3339, from is inclusive and
+// to exclusive
+type TagListQuery struct {
+	Name        string `query:"name" binding:"omitempty,max=50"`
+	Slug        string `query:"slug" binding:"omitempty,max=50"`
+	Prefix      string `query:"prefix" binding:"omitempty,max=50"`
+	CreatedFrom string `query:"created_from" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	CreatedTo   string `query:"created_to" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	UpdatedFrom string `query:"updated_from" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	UpdatedTo   string `query:"updated_to" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+}
+
+// TagRecord is one tag in an export or import file
+type TagRecord struct {
+	Name      string     `json:"name"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+type TagExportQuery struct {
+	Format string `query:"format" binding:"omitempty,oneof=json ndjson csv"`
+}
+
+type TagImportQuery struct {
+	// Format defaults to the Content-Type of the body
+	Format   string `query:"format" binding:"omitempty,oneof=json ndjson csv"`
+	Strategy string `query:"strategy" binding:"omitempty,oneof=skip overwrite merge"`
+	DryRun   bool   `query:"dry_run"`
+}
+
+// TagImportItem is the outcome of one record of an import file; line is the
+// record number, starting at 1
+type TagImportItem struct {
+	Line   int                   `json:"line,omitempty"`
+	Name   string                `json:"name"`
+	Action string                `json:"action" enums:"created,updated,unchanged,skipped,deleted,invalid"`
+	Errors []apperror.FieldError `json:"errors,omitempty"`
+}
+
+type TagImportReport struct {
+	DryRun    bool            `json:"dry_run"`
+	Strategy  string          `json:"strategy"`
+	Created   int             `json:"created"`
+	Updated   int             `json:"updated"`
+	Unchanged int             `json:"unchanged"`
+	Skipped   int             `json:"skipped"`
+	Deleted   int             `json:"deleted"`
+	Invalid   int             `json:"invalid"`
+	Items     []TagImportItem `json:"items"`
 }
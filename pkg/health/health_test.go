@@ -0,0 +1,52 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRegistryRun(t *testing.T) {
+	registry := NewRegistry(50 * time.Millisecond)
+	registry.Register("mongodb", func(ctx context.Context) error { return nil })
+
+	report := registry.Run(context.Background())
+	if report.Status != StatusUp || report.Checks["mongodb"].Status != StatusUp {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	registry.Register("redis", func(ctx context.Context) error { return errors.New("connection refused") })
+	registry.Register("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	report = registry.Run(context.Background())
+	if report.Status != StatusDown {
+		t.Fatalf("Status = %q, want %q", report.Status, StatusDown)
+	}
+	if got := report.Checks["redis"]; got.Status != StatusDown || got.Error != "connection refused" {
+		t.Errorf("redis = %+v", got)
+	}
+	if got := report.Checks["slow"]; got.Status != StatusDown || got.Error != context.DeadlineExceeded.Error() {
+		t.Errorf("slow = %+v", got)
+	}
+	if got := report.Checks["mongodb"]; got.Status != StatusUp {
+		t.Errorf("mongodb = %+v", got)
+	}
+}
+
+func TestRegistryDrain(t *testing.T) {
+	registry := NewRegistry(50 * time.Millisecond)
+	registry.Register("mongodb", func(ctx context.Context) error { return nil })
+	registry.Drain()
+
+	report := registry.Run(context.Background())
+	if report.Status != StatusDown {
+		t.Fatalf("Status = %q while draining, want %q", report.Status, StatusDown)
+	}
+	if got := report.Checks["server"]; got.Status != StatusDown || got.Error != "shutting down" {
+		t.Errorf("server = %+v", got)
+	}
+}
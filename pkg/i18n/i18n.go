@@ -0,0 +1,180 @@
+// Package i18n negotiates the response language and translates validation
+// and error messages. English comes with the validator; the Thai catalogs
+// live in this package.
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/th"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+)
+
+const (
+	English = "en"
+	Thai    = "th"
+)
+
+// Supported lists the languages with complete catalogs
+var Supported = []string{English, Thai}
+
+// invalidMessages is used for tags no catalog knows, so a field error is
+// never reported in the validator's internal format
+var invalidMessages = map[string]string{
+	English: "{0} is invalid",
+	Thai:    "{0} ไม่ถูกต้อง",
+}
+
+type Translator struct {
+	universal *ut.UniversalTranslator
+	// messages holds translated error details by language and error code
+	messages map[string]map[string]string
+}
+
+// New registers the validation catalogs of every supported language on v
+func New(v *validator.Validate) (*Translator, error) {
+	english := en.New()
+	t := &Translator{
+		universal: ut.New(english, english, th.New()),
+		messages:  map[string]map[string]string{Thai: thaiMessages},
+	}
+
+	if err := en_translations.RegisterDefaultTranslations(v, t.For(English)); err != nil {
+		return nil, fmt.Errorf("register en translations: %w", err)
+	}
+	if err := registerThai(v, t.For(Thai)); err != nil {
+		return nil, fmt.Errorf("register th translations: %w", err)
+	}
+
+	for lang, message := range invalidMessages {
+		if err := t.For(lang).Add("invalid", message, false); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// For returns the translator for lang, or English when lang is unsupported
+func (t *Translator) For(lang string) ut.Translator {
+	trans, found := t.universal.GetTranslator(lang)
+	if !found {
+		trans, _ = t.universal.GetTranslator(English)
+	}
+	return trans
+}
+
+// RegisterValidation adds the messages of a custom validation tag, keyed by
+// language; {0} is the field and {1} the tag parameter
+func (t *Translator) RegisterValidation(v *validator.Validate, tag string, messages map[string]string) error {
+	for _, lang := range Supported {
+		message, ok := messages[lang]
+		if !ok {
+			return fmt.Errorf("validation tag %q has no %s message", tag, lang)
+		}
+		if err := v.RegisterTranslation(tag, t.For(lang), addFunc(tag, message), translateFunc(tag)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Field translates a single validation failure
+func (t *Translator) Field(lang string, fe validator.FieldError) string {
+	trans := t.For(lang)
+	if message := fe.Translate(trans); message != fe.Error() {
+		return message
+	}
+
+	message, err := trans.T("invalid", fe.Field())
+	if err != nil {
+		return fe.Error()
+	}
+	return message
+}
+
+// Message translates the detail of an error code, falling back to the
+// English message carried by the error
+func (t *Translator) Message(lang, code, fallback string) string {
+	if message, ok := t.messages[lang][code]; ok {
+		return message
+	}
+	return fallback
+}
+
+// Negotiate picks the supported language the client prefers most according
+// to an Accept-Language header, matching regional variants such as th-TH by
+// their base language
+func Negotiate(acceptLanguage, fallback string) string {
+	type preference struct {
+		tag     string
+		quality float64
+	}
+
+	var prefs []preference
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality <= 0 {
+			continue
+		}
+		prefs = append(prefs, preference{tag: strings.ToLower(tag), quality: quality})
+	}
+
+	sort.SliceStable(prefs, func(i, j int) bool { return prefs[i].quality > prefs[j].quality })
+
+	for _, pref := range prefs {
+		if pref.tag == "*" {
+			return fallback
+		}
+		base, _, _ := strings.Cut(pref.tag, "-")
+		for _, lang := range Supported {
+			if base == lang {
+				return lang
+			}
+		}
+	}
+	return fallback
+}
+
+// IsSupported reports whether lang has a catalog
+func IsSupported(lang string) bool {
+	for _, supported := range Supported {
+		if lang == supported {
+			return true
+		}
+	}
+	return false
+}
+
+func addFunc(key, message string) validator.RegisterTranslationsFunc {
+	return func(trans ut.Translator) error {
+		return trans.Add(key, message, true)
+	}
+}
+
+func translateFunc(key string) validator.TranslationFunc {
+	return func(trans ut.Translator, fe validator.FieldError) string {
+		message, err := trans.T(key, fe.Field(), fe.Param())
+		if err != nil {
+			return fe.Error()
+		}
+		return message
+	}
+}
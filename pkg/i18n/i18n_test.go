@@ -0,0 +1,173 @@
+package i18n
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "", want: English},
+		{header: "th", want: Thai},
+		{header: "th-TH,th;q=0.9,en;q=0.8", want: Thai},
+		{header: "en-US,en;q=0.9,th;q=0.8", want: English},
+		{header: "fr-FR, th;q=0.5", want: Thai},
+		{header: "en;q=0.2, th;q=0.7", want: Thai},
+		{header: "th;q=0, en", want: English},
+		{header: "fr, de", want: English},
+		{header: "*", want: English},
+		{header: "th;q=abc, en", want: English},
+	}
+
+	for _, tt := range tests {
+		if got := Negotiate(tt.header, English); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+
+	if got := Negotiate("fr", Thai); got != Thai {
+		t.Errorf("Negotiate with fallback = %q, want %q", got, Thai)
+	}
+}
+
+type sample struct {
+	Name     string   `json:"name" validate:"required"`
+	Code     string   `json:"code" validate:"len=3"`
+	Title    string   `json:"title" validate:"max=5"`
+	Count    int      `json:"count" validate:"min=1"`
+	Tags     []string `json:"tags" validate:"max=1"`
+	Email    string   `json:"email" validate:"email"`
+	Website  string   `json:"website" validate:"url"`
+	Status   string   `json:"status" validate:"oneof=draft published"`
+	Password string   `json:"password" validate:"password_rule"`
+}
+
+func newTestValidator(t *testing.T) (*validator.Validate, *Translator) {
+	t.Helper()
+
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		return name
+	})
+	if err := v.RegisterValidation("password_rule", func(fl validator.FieldLevel) bool { return false }); err != nil {
+		t.Fatalf("RegisterValidation: %v", err)
+	}
+
+	translator, err := New(v)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return v, translator
+}
+
+func TestFieldTranslations(t *testing.T) {
+	v, translator := newTestValidator(t)
+
+	err := v.Struct(sample{
+		Code:     "ab",
+		Title:    "too long",
+		Tags:     []string{"a", "b"},
+		Email:    "not-an-email",
+		Website:  "not a url",
+		Status:   "archived",
+		Password: "x",
+	})
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		t.Fatalf("err = %v, want validation errors", err)
+	}
+
+	want := map[string]map[string]string{
+		English: {
+			"name":     "name is a required field",
+			"code":     "code must be 3 characters in length",
+			"title":    "title must be a maximum of 5 characters in length",
+			"count":    "count must be 1 or greater",
+			"tags":     "tags must contain at maximum 1 item",
+			"email":    "email must be a valid email address",
+			"website":  "website must be a valid URL",
+			"status":   "status must be one of [draft published]",
+			"password": "password is invalid",
+		},
+		Thai: {
+			"name":     "name จำเป็นต้องระบุ",
+			"code":     "code ต้องมีความยาว 3 ตัวอักษร",
+			"title":    "title ต้องมีความยาวไม่เกิน 5 ตัวอักษร",
+			"count":    "count ต้องมีค่าอย่างน้อย 1",
+			"tags":     "tags ต้องมีไม่เกิน 1 รายการ",
+			"email":    "email ต้องเป็นอีเมลที่ถูกต้อง",
+			"website":  "website ต้องเป็น URL ที่ถูกต้อง",
+			"status":   "status ต้องเป็นค่าใดค่าหนึ่งต่อไปนี้ [draft published]",
+			"password": "password ไม่ถูกต้อง",
+		},
+	}
+
+	for lang, messages := range want {
+		if len(validationErrors) != len(messages) {
+			t.Fatalf("got %d errors, want %d: %v", len(validationErrors), len(messages), validationErrors)
+		}
+		for _, fe := range validationErrors {
+			if got := translator.Field(lang, fe); got != messages[fe.Field()] {
+				t.Errorf("%s %s: got %q, want %q", lang, fe.Field(), got, messages[fe.Field()])
+			}
+		}
+	}
+}
+
+func TestRegisterValidation(t *testing.T) {
+	v, translator := newTestValidator(t)
+
+	if err := translator.RegisterValidation(v, "password_rule", map[string]string{English: "{0} is weak"}); err == nil {
+		t.Fatal("expected error for a missing Thai message")
+	}
+	if err := translator.RegisterValidation(v, "password_rule", map[string]string{
+		English: "{0} is weak",
+		Thai:    "{0} ไม่ปลอดภัย",
+	}); err != nil {
+		t.Fatalf("RegisterValidation: %v", err)
+	}
+
+	var validationErrors validator.ValidationErrors
+	errors.As(v.Var("x", "password_rule"), &validationErrors)
+	if got := translator.Field(Thai, validationErrors[0]); !strings.HasSuffix(got, "ไม่ปลอดภัย") {
+		t.Errorf("Thai message = %q", got)
+	}
+}
+
+// englishTags are the tags registered by the validator's English catalog
+var englishTags = strings.Fields(`required required_if required_unless required_with required_with_all
+required_without required_without_all excluded_if excluded_unless excluded_with excluded_with_all
+excluded_without excluded_without_all isdefault len min max eq ne lt lte gt gte eqfield eqcsfield
+necsfield gtcsfield gtecsfield ltcsfield ltecsfield nefield gtfield gtefield ltfield ltefield alpha
+alphanum numeric number hexadecimal hexcolor rgb rgba hsl hsla e164 email url uri base64 contains
+containsany excludes excludesall excludesrune isbn isbn10 isbn13 issn uuid uuid3 uuid4 uuid5 ulid
+ascii printascii multibyte datauri latitude longitude ssn ipv4 ipv6 ip cidr cidrv4 cidrv6 tcp_addr
+tcp4_addr tcp6_addr udp_addr udp4_addr udp6_addr ip_addr ip4_addr ip6_addr unix_addr mac fqdn unique
+iscolor cron oneof json jwt lowercase uppercase datetime postcode_iso3166_alpha2
+postcode_iso3166_alpha2_field boolean image cve`)
+
+func TestThaiCatalogCoversEnglishTags(t *testing.T) {
+	for _, tag := range englishTags {
+		_, simple := thaiValidation[tag]
+		_, sized := thaiSizeValidation[tag]
+		if !simple && !sized {
+			t.Errorf("tag %q has no Thai message", tag)
+		}
+	}
+
+	_, translator := newTestValidator(t)
+	if got := translator.Message(Thai, "TAG_NOT_FOUND", "Tag not found"); got != "ไม่พบแท็ก" {
+		t.Errorf("Thai message = %q", got)
+	}
+	if got := translator.Message(English, "TAG_NOT_FOUND", "Tag not found"); got != "Tag not found" {
+		t.Errorf("English message = %q", got)
+	}
+}
@@ -0,0 +1,261 @@
+package i18n
+
+import (
+	"reflect"
+	"time"
+
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+)
+
+// thaiMessages translates the details of API error codes
+var thaiMessages = map[string]string{
+	"BAD_REQUEST":          "คำขอไม่ถูกต้อง",
+	"INVALID_REQUEST_BODY": "รูปแบบข้อมูลในคำขอไม่ถูกต้อง",
+	"INVALID_QUERY":        "พารามิเตอร์ในคิวรีไม่ถูกต้อง",
+	"INVALID_PATH_PARAMS":  "พารามิเตอร์ในพาธไม่ถูกต้อง",
+	"VALIDATION_FAILED":    "ข้อมูลในคำขอไม่ผ่านการตรวจสอบ",
+	"UNAUTHORIZED":         "กรุณาเข้าสู่ระบบ",
+	"FORBIDDEN":            "ไม่มีสิทธิ์เข้าถึง",
+	"NOT_FOUND":            "ไม่พบข้อมูลที่ร้องขอ",
+	"METHOD_NOT_ALLOWED":   "ไม่รองรับเมธอดนี้",
+	"CONFLICT":             "ข้อมูลขัดแย้งกับข้อมูลที่มีอยู่",
+	"PAYLOAD_TOO_LARGE":    "ข้อมูลในคำขอมีขนาดใหญ่เกินไป",
+	"RATE_LIMITED":         "มีการส่งคำขอมากเกินไป กรุณาลองใหม่ภายหลัง",
+	"REQUEST_TIMEOUT":      "คำขอใช้เวลานานเกินไป",
+	"REQUEST_CANCELLED":    "คำขอถูกยกเลิก",
+	"SERVICE_UNAVAILABLE":  "บริการไม่พร้อมใช้งานชั่วคราว",
+	"INTERNAL_ERROR":       "เกิดข้อผิดพลาดภายในระบบ",
+
+	"TAG_NOT_FOUND":              "ไม่พบแท็ก",
+	"TAG_ALREADY_EXISTS":         "มีแท็กนี้อยู่แล้ว",
+	"TAG_DUPLICATED_IN_FILE":     "แท็กนี้ซ้ำกันในไฟล์",
+	"TAG_IMPORT_FORMAT_REQUIRED": "ต้องระบุรูปแบบไฟล์ json, ndjson หรือ csv",
+
+	"AUTH_LOGIN_FAILED":            "เข้าสู่ระบบไม่สำเร็จ",
+	"AUTH_INVALID_STATE":           "สถานะการเข้าสู่ระบบไม่ถูกต้อง",
+	"AUTH_CODE_REQUIRED":           "ต้องระบุรหัสการอนุญาต",
+	"INVALID_AUTHORIZATION_HEADER": "ส่วนหัว Authorization ไม่ถูกต้อง",
+	"INVALID_TOKEN":                "โทเค็นไม่ถูกต้องหรือหมดอายุ",
+	"AUTHENTICATION_REQUIRED":      "กรุณาเข้าสู่ระบบ",
+	"INSUFFICIENT_PERMISSIONS":     "สิทธิ์ไม่เพียงพอ",
+
+	"TENANT_MISMATCH":    "เวิร์กสเปซไม่ตรงกับโทเค็น",
+	"TENANT_NOT_ALLOWED": "ต้องระบุเวิร์กสเปซผ่านโทเค็น",
+	"TENANT_REQUIRED":    "ต้องระบุเวิร์กสเปซ",
+	"INVALID_TENANT":     "เวิร์กสเปซไม่ถูกต้อง",
+}
+
+// thaiValidation covers the same tags as the validator's English catalog;
+// {0} is the field and {1} the tag parameter
+var thaiValidation = map[string]string{
+	"required":             "{0} จำเป็นต้องระบุ",
+	"required_if":          "{0} จำเป็นต้องระบุ",
+	"required_unless":      "{0} จำเป็นต้องระบุ",
+	"required_with":        "{0} จำเป็นต้องระบุ",
+	"required_with_all":    "{0} จำเป็นต้องระบุ",
+	"required_without":     "{0} จำเป็นต้องระบุ",
+	"required_without_all": "{0} จำเป็นต้องระบุ",
+	"excluded_if":          "{0} ต้องไม่ระบุ",
+	"excluded_unless":      "{0} ต้องไม่ระบุ",
+	"excluded_with":        "{0} ต้องไม่ระบุ",
+	"excluded_with_all":    "{0} ต้องไม่ระบุ",
+	"excluded_without":     "{0} ต้องไม่ระบุ",
+	"excluded_without_all": "{0} ต้องไม่ระบุ",
+	"isdefault":            "{0} ต้องเป็นค่าเริ่มต้น",
+
+	"eq":         "{0} ต้องเท่ากับ {1}",
+	"ne":         "{0} ต้องไม่เท่ากับ {1}",
+	"eqfield":    "{0} ต้องตรงกับ {1}",
+	"eqcsfield":  "{0} ต้องตรงกับ {1}",
+	"nefield":    "{0} ต้องไม่ตรงกับ {1}",
+	"necsfield":  "{0} ต้องไม่ตรงกับ {1}",
+	"gtfield":    "{0} ต้องมากกว่า {1}",
+	"gtcsfield":  "{0} ต้องมากกว่า {1}",
+	"gtefield":   "{0} ต้องมากกว่าหรือเท่ากับ {1}",
+	"gtecsfield": "{0} ต้องมากกว่าหรือเท่ากับ {1}",
+	"ltfield":    "{0} ต้องน้อยกว่า {1}",
+	"ltcsfield":  "{0} ต้องน้อยกว่า {1}",
+	"ltefield":   "{0} ต้องน้อยกว่าหรือเท่ากับ {1}",
+	"ltecsfield": "{0} ต้องน้อยกว่าหรือเท่ากับ {1}",
+
+	"alpha":       "{0} ต้องมีเฉพาะตัวอักษรภาษาอังกฤษ",
+	"alphanum":    "{0} ต้องมีเฉพาะตัวอักษรภาษาอังกฤษและตัวเลข",
+	"numeric":     "{0} ต้องเป็นค่าตัวเลขที่ถูกต้อง",
+	"number":      "{0} ต้องเป็นตัวเลขที่ถูกต้อง",
+	"hexadecimal": "{0} ต้องเป็นเลขฐานสิบหกที่ถูกต้อง",
+	"hexcolor":    "{0} ต้องเป็นสี HEX ที่ถูกต้อง",
+	"rgb":         "{0} ต้องเป็นสี RGB ที่ถูกต้อง",
+	"rgba":        "{0} ต้องเป็นสี RGBA ที่ถูกต้อง",
+	"hsl":         "{0} ต้องเป็นสี HSL ที่ถูกต้อง",
+	"hsla":        "{0} ต้องเป็นสี HSLA ที่ถูกต้อง",
+	"iscolor":     "{0} ต้องเป็นสีที่ถูกต้อง",
+	"e164":        "{0} ต้องเป็นหมายเลขโทรศัพท์รูปแบบ E.164 ที่ถูกต้อง",
+	"email":       "{0} ต้องเป็นอีเมลที่ถูกต้อง",
+	"url":         "{0} ต้องเป็น URL ที่ถูกต้อง",
+	"uri":         "{0} ต้องเป็น URI ที่ถูกต้อง",
+	"base64":      "{0} ต้องเป็นสตริง Base64 ที่ถูกต้อง",
+	"datauri":     "{0} ต้องเป็น Data URI ที่ถูกต้อง",
+	"json":        "{0} ต้องเป็นสตริง JSON ที่ถูกต้อง",
+	"jwt":         "{0} ต้องเป็นสตริง JWT ที่ถูกต้อง",
+	"boolean":     "{0} ต้องเป็นค่าบูลีนที่ถูกต้อง",
+	"image":       "{0} ต้องเป็นไฟล์รูปภาพที่ถูกต้อง",
+	"cron":        "{0} ต้องเป็นนิพจน์ cron ที่ถูกต้อง",
+	"cve":         "{0} ต้องเป็นรหัส CVE ที่ถูกต้อง",
+
+	"contains":     "{0} ต้องมีข้อความ '{1}'",
+	"containsany":  "{0} ต้องมีอักขระอย่างน้อยหนึ่งตัวจาก '{1}'",
+	"excludes":     "{0} ต้องไม่มีข้อความ '{1}'",
+	"excludesall":  "{0} ต้องไม่มีอักขระใดจาก '{1}'",
+	"excludesrune": "{0} ต้องไม่มี '{1}'",
+	"oneof":        "{0} ต้องเป็นค่าใดค่าหนึ่งต่อไปนี้ [{1}]",
+	"unique":       "{0} ต้องมีค่าไม่ซ้ำกัน",
+	"lowercase":    "{0} ต้องเป็นตัวพิมพ์เล็กทั้งหมด",
+	"uppercase":    "{0} ต้องเป็นตัวพิมพ์ใหญ่ทั้งหมด",
+	"ascii":        "{0} ต้องมีเฉพาะอักขระ ASCII",
+	"printascii":   "{0} ต้องมีเฉพาะอักขระ ASCII ที่พิมพ์ได้",
+	"multibyte":    "{0} ต้องมีอักขระแบบมัลติไบต์",
+	"datetime":     "{0} ไม่ตรงกับรูปแบบ {1}",
+
+	"isbn":   "{0} ต้องเป็นหมายเลข ISBN ที่ถูกต้อง",
+	"isbn10": "{0} ต้องเป็นหมายเลข ISBN-10 ที่ถูกต้อง",
+	"isbn13": "{0} ต้องเป็นหมายเลข ISBN-13 ที่ถูกต้อง",
+	"issn":   "{0} ต้องเป็นหมายเลข ISSN ที่ถูกต้อง",
+	"uuid":   "{0} ต้องเป็น UUID ที่ถูกต้อง",
+	"uuid3":  "{0} ต้องเป็น UUID เวอร์ชัน 3 ที่ถูกต้อง",
+	"uuid4":  "{0} ต้องเป็น UUID เวอร์ชัน 4 ที่ถูกต้อง",
+	"uuid5":  "{0} ต้องเป็น UUID เวอร์ชัน 5 ที่ถูกต้อง",
+	"ulid":   "{0} ต้องเป็น ULID ที่ถูกต้อง",
+	"ssn":    "{0} ต้องเป็นหมายเลข SSN ที่ถูกต้อง",
+
+	"latitude":  "{0} ต้องเป็นค่าละติจูดที่ถูกต้อง",
+	"longitude": "{0} ต้องเป็นค่าลองจิจูดที่ถูกต้อง",
+
+	"ip":        "{0} ต้องเป็นที่อยู่ IP ที่ถูกต้อง",
+	"ipv4":      "{0} ต้องเป็นที่อยู่ IPv4 ที่ถูกต้อง",
+	"ipv6":      "{0} ต้องเป็นที่อยู่ IPv6 ที่ถูกต้อง",
+	"cidr":      "{0} ต้องเป็นสัญกรณ์ CIDR ที่ถูกต้อง",
+	"cidrv4":    "{0} ต้องเป็นสัญกรณ์ CIDR ของที่อยู่ IPv4 ที่ถูกต้อง",
+	"cidrv6":    "{0} ต้องเป็นสัญกรณ์ CIDR ของที่อยู่ IPv6 ที่ถูกต้อง",
+	"tcp_addr":  "{0} ต้องเป็นที่อยู่ TCP ที่ถูกต้อง",
+	"tcp4_addr": "{0} ต้องเป็นที่อยู่ IPv4 TCP ที่ถูกต้อง",
+	"tcp6_addr": "{0} ต้องเป็นที่อยู่ IPv6 TCP ที่ถูกต้อง",
+	"udp_addr":  "{0} ต้องเป็นที่อยู่ UDP ที่ถูกต้อง",
+	"udp4_addr": "{0} ต้องเป็นที่อยู่ IPv4 UDP ที่ถูกต้อง",
+	"udp6_addr": "{0} ต้องเป็นที่อยู่ IPv6 UDP ที่ถูกต้อง",
+	"ip_addr":   "{0} ต้องเป็นที่อยู่ IP ที่สามารถ resolve ได้",
+	"ip4_addr":  "{0} ต้องเป็นที่อยู่ IPv4 ที่สามารถ resolve ได้",
+	"ip6_addr":  "{0} ต้องเป็นที่อยู่ IPv6 ที่สามารถ resolve ได้",
+	"unix_addr": "{0} ต้องเป็นที่อยู่ UNIX ที่สามารถ resolve ได้",
+	"mac":       "{0} ต้องเป็นที่อยู่ MAC ที่ถูกต้อง",
+	"fqdn":      "{0} ต้องเป็นชื่อโดเมนแบบเต็ม (FQDN) ที่ถูกต้อง",
+
+	"postcode_iso3166_alpha2":       "{0} ไม่ตรงกับรูปแบบรหัสไปรษณีย์ของประเทศ {1}",
+	"postcode_iso3166_alpha2_field": "{0} ไม่ตรงกับรูปแบบรหัสไปรษณีย์ของประเทศในฟิลด์ {1}",
+}
+
+// sizeMessages depend on whether the field is text, a collection, a number
+// or a time; time comparisons without a parameter are against now
+type sizeMessages struct {
+	text, items, number, time string
+}
+
+var thaiSizeValidation = map[string]sizeMessages{
+	"len": {
+		text:   "{0} ต้องมีความยาว {1} ตัวอักษร",
+		items:  "{0} ต้องมี {1} รายการ",
+		number: "{0} ต้องเท่ากับ {1}",
+	},
+	"min": {
+		text:   "{0} ต้องมีความยาวอย่างน้อย {1} ตัวอักษร",
+		items:  "{0} ต้องมีอย่างน้อย {1} รายการ",
+		number: "{0} ต้องมีค่าอย่างน้อย {1}",
+	},
+	"max": {
+		text:   "{0} ต้องมีความยาวไม่เกิน {1} ตัวอักษร",
+		items:  "{0} ต้องมีไม่เกิน {1} รายการ",
+		number: "{0} ต้องมีค่าไม่เกิน {1}",
+	},
+	"lt": {
+		text:   "{0} ต้องมีความยาวน้อยกว่า {1} ตัวอักษร",
+		items:  "{0} ต้องมีน้อยกว่า {1} รายการ",
+		number: "{0} ต้องน้อยกว่า {1}",
+		time:   "{0} ต้องเป็นเวลาก่อนปัจจุบัน",
+	},
+	"lte": {
+		text:   "{0} ต้องมีความยาวไม่เกิน {1} ตัวอักษร",
+		items:  "{0} ต้องมีไม่เกิน {1} รายการ",
+		number: "{0} ต้องน้อยกว่าหรือเท่ากับ {1}",
+		time:   "{0} ต้องเป็นเวลาก่อนหรือเท่ากับปัจจุบัน",
+	},
+	"gt": {
+		text:   "{0} ต้องมีความยาวมากกว่า {1} ตัวอักษร",
+		items:  "{0} ต้องมีมากกว่า {1} รายการ",
+		number: "{0} ต้องมากกว่า {1}",
+		time:   "{0} ต้องเป็นเวลาหลังปัจจุบัน",
+	},
+	"gte": {
+		text:   "{0} ต้องมีความยาวอย่างน้อย {1} ตัวอักษร",
+		items:  "{0} ต้องมีอย่างน้อย {1} รายการ",
+		number: "{0} ต้องมากกว่าหรือเท่ากับ {1}",
+		time:   "{0} ต้องเป็นเวลาหลังหรือเท่ากับปัจจุบัน",
+	},
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func registerThai(v *validator.Validate, trans ut.Translator) error {
+	for tag, message := range thaiValidation {
+		if err := v.RegisterTranslation(tag, trans, addFunc(tag, message), translateFunc(tag)); err != nil {
+			return err
+		}
+	}
+
+	for tag, messages := range thaiSizeValidation {
+		if err := v.RegisterTranslation(tag, trans, addSizeFunc(tag, messages), translateSizeFunc(tag)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addSizeFunc(tag string, messages sizeMessages) validator.RegisterTranslationsFunc {
+	return func(trans ut.Translator) error {
+		variants := map[string]string{
+			tag + "-text":   messages.text,
+			tag + "-items":  messages.items,
+			tag + "-number": messages.number,
+		}
+		if messages.time != "" {
+			variants[tag+"-time"] = messages.time
+		}
+		for key, message := range variants {
+			if err := trans.Add(key, message, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func translateSizeFunc(tag string) validator.TranslationFunc {
+	return func(trans ut.Translator, fe validator.FieldError) string {
+		variant := "-number"
+		switch fe.Kind() {
+		case reflect.String:
+			variant = "-text"
+		case reflect.Slice, reflect.Map, reflect.Array:
+			variant = "-items"
+		case reflect.Struct:
+			if fe.Type() == timeType && fe.Param() == "" {
+				variant = "-time"
+			}
+		}
+
+		message, err := trans.T(tag+variant, fe.Field(), fe.Param())
+		if err != nil {
+			return fe.Error()
+		}
+		return message
+	}
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"pre-test-gallery-service/pkg/apperror"
+	"pre-test-gallery-service/pkg/auth"
+	"pre-test-gallery-service/pkg/utils"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	ErrInvalidAuthorizationHeader = apperror.New(apperror.KindUnauthorized, "INVALID_AUTHORIZATION_HEADER", "Invalid authorization header")
+	ErrInvalidToken               = apperror.New(apperror.KindUnauthorized, "INVALID_TOKEN", "Invalid or expired token")
+	ErrAuthenticationRequired     = apperror.New(apperror.KindUnauthorized, "AUTHENTICATION_REQUIRED", "Authentication required")
+	ErrInsufficientPermissions    = apperror.New(apperror.KindForbidden, "INSUFFICIENT_PERMISSIONS", "Insufficient permissions")
+)
+
+// Authenticate resolves the bearer token when one is sent and stores the
+// user in locals; anonymous requests pass through untouched
+func Authenticate(tokenIssuer *auth.TokenIssuer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		header := c.Get(fiber.HeaderAuthorization)
+		if header == "" {
+			return c.Next()
+		}
+
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			return utils.SendError(c, ErrInvalidAuthorizationHeader)
+		}
+
+		claims, err := tokenIssuer.Parse(strings.TrimSpace(token))
+		if err != nil {
+			return utils.SendError(c, ErrInvalidToken.Wrap(err))
+		}
+
+		c.Locals("user", claims.Subject)
+		c.Locals("role", claims.Role)
+		c.Locals("claims", claims)
+		if name, ok := claims.APIKey(); ok {
+			c.Locals("api_key", name)
+		}
+		if claims.Tenant != "" {
+			c.Locals("tenant", claims.Tenant)
+		}
+
+		return c.Next()
+	}
+}
+
+// RequireBearerToken guards internal endpoints such as /metrics with a
+// static token shared with the scraper
+func RequireBearerToken(token string) fiber.Handler {
+	want := []byte(token)
+	return func(c *fiber.Ctx) error {
+		header := c.Get(fiber.HeaderAuthorization)
+		if header == "" {
+			return utils.SendError(c, ErrAuthenticationRequired)
+		}
+
+		got, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			return utils.SendError(c, ErrInvalidAuthorizationHeader)
+		}
+		if subtle.ConstantTimeCompare([]byte(strings.TrimSpace(got)), want) != 1 {
+			return utils.SendError(c, ErrInvalidToken)
+		}
+
+		return c.Next()
+	}
+}
+
+// RequireRole rejects requests whose authenticated user has none of the roles
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, ok := c.Locals("user").(string); !ok {
+			return utils.SendError(c, ErrAuthenticationRequired)
+		}
+
+		role, _ := c.Locals("role").(string)
+		for _, allowed := range roles {
+			if role == allowed {
+				return c.Next()
+			}
+		}
+
+		return utils.SendError(c, ErrInsufficientPermissions)
+	}
+}
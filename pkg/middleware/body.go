@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"io"
+	"pre-test-gallery-service/pkg/apperror"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ErrBodyTooLarge answers requests whose body exceeds the app's BodyLimit
+var ErrBodyTooLarge = apperror.New(apperror.KindTooLarge, apperror.CodePayloadTooLarge, "Request body is too large")
+
+// BufferBody reads a streamed request body into memory, up to the app's
+// BodyLimit. With StreamRequestBody, fasthttp hands larger bodies over as a
+// stream instead of rejecting them, so routes that call c.Body() need this;
+// routes that read the stream themselves skip it
+func BufferBody() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		stream := c.Context().RequestBodyStream()
+		if stream == nil {
+			return c.Next()
+		}
+
+		// The rest of a rejected body is left unread, so the connection
+		// cannot carry another request
+		limit := c.App().Config().BodyLimit
+		if c.Request().Header.ContentLength() > limit {
+			c.Context().SetConnectionClose()
+			return ErrBodyTooLarge
+		}
+		body, err := io.ReadAll(io.LimitReader(stream, int64(limit)+1))
+		if err != nil {
+			c.Context().SetConnectionClose()
+			return apperror.ErrInvalidRequestBody.Wrap(err)
+		}
+		if len(body) > limit {
+			c.Context().SetConnectionClose()
+			return ErrBodyTooLarge
+		}
+
+		c.Request().SetBody(body)
+		return c.Next()
+	}
+}
+
+// bodySize is the length of the request body, taken from Content-Length
+// when the body is still a stream so that logging does not read it
+func bodySize(c *fiber.Ctx) int {
+	if c.Context().RequestBodyStream() != nil {
+		return max(c.Request().Header.ContentLength(), 0)
+	}
+	return len(c.Request().Body())
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"pre-test-gallery-service/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestBufferBody(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: utils.ErrorHandler, StreamRequestBody: true, BodyLimit: 16})
+	app.Use(BufferBody())
+	app.Post("/", func(c *fiber.Ctx) error {
+		return c.SendString(strconv.Itoa(len(c.Body())))
+	})
+
+	tests := []struct {
+		name    string
+		body    string
+		chunked bool
+		want    int
+	}{
+		{name: "within the limit", body: "0123456789abcdef", want: fiber.StatusOK},
+		{name: "over the limit", body: "0123456789abcdefg", want: fiber.StatusRequestEntityTooLarge},
+		{name: "chunked within the limit", body: "0123456789", chunked: true, want: fiber.StatusOK},
+		{name: "chunked over the limit", body: strings.Repeat("x", 64), chunked: true, want: fiber.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1
+				req.TransferEncoding = []string{"chunked"}
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("app.Test: %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.want {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.want, body)
+			}
+			if tt.want == fiber.StatusOK && string(body) != strconv.Itoa(len(tt.body)) {
+				t.Errorf("handler read %s bytes, want %d", body, len(tt.body))
+			}
+		})
+	}
+}
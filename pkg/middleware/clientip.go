@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var DefaultClientIPHeaders = []string{"Forwarded", "X-Forwarded-For", "X-Real-IP"}
+
+// ClientIPResolver finds the real client address behind reverse proxies.
+// Forwarding headers are only believed when the connection comes from a
+// trusted proxy, and proxy chains are walked from the right so a client
+// cannot spoof its address by sending the headers itself.
+type ClientIPResolver struct {
+	trusted []netip.Prefix
+	headers []string
+}
+
+// NewClientIPResolver accepts trusted proxies as CIDRs or single addresses
+func NewClientIPResolver(trustedProxies, headers []string) (*ClientIPResolver, error) {
+	r := &ClientIPResolver{headers: headers}
+	if len(r.headers) == 0 {
+		r.headers = DefaultClientIPHeaders
+	}
+
+	for _, raw := range trustedProxies {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+
+		if !strings.Contains(raw, "/") {
+			addr, err := netip.ParseAddr(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", raw)
+			}
+			r.trusted = append(r.trusted, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q", raw)
+		}
+		r.trusted = append(r.trusted, prefix.Masked())
+	}
+
+	for _, header := range r.headers {
+		switch strings.ToLower(header) {
+		case "forwarded", "x-forwarded-for", "x-real-ip":
+		default:
+			return nil, fmt.Errorf("unsupported client IP header %q", header)
+		}
+	}
+
+	return r, nil
+}
+
+func (r *ClientIPResolver) isTrusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range r.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns the client address given the peer address and a header getter
+func (r *ClientIPResolver) Resolve(remoteIP string, header func(string) string) string {
+	remote, err := netip.ParseAddr(remoteIP)
+	if err != nil || !r.isTrusted(remote) {
+		return remoteIP
+	}
+
+	for _, name := range r.headers {
+		value := header(name)
+		if value == "" {
+			continue
+		}
+
+		var chain []string
+		switch strings.ToLower(name) {
+		case "forwarded":
+			chain = parseForwarded(value)
+		case "x-forwarded-for":
+			chain = strings.Split(value, ",")
+		case "x-real-ip":
+			chain = []string{value}
+		}
+
+		if ip, ok := r.fromChain(chain); ok {
+			return ip
+		}
+	}
+
+	return remote.Unmap().String()
+}
+
+// fromChain walks the hops right to left and returns the first one that is
+// not a trusted proxy
+func (r *ClientIPResolver) fromChain(chain []string) (string, bool) {
+	var leftmost netip.Addr
+	for i := len(chain) - 1; i >= 0; i-- {
+		addr, ok := parseHop(chain[i])
+		if !ok {
+			// An unknown or obfuscated hop cannot be trusted any further
+			return "", false
+		}
+		if !r.isTrusted(addr) {
+			return addr.String(), true
+		}
+		leftmost = addr
+	}
+
+	if leftmost.IsValid() {
+		return leftmost.String(), true
+	}
+	return "", false
+}
+
+func parseHop(hop string) (netip.Addr, bool) {
+	hop = strings.Trim(strings.TrimSpace(hop), `"`)
+	if hop == "" {
+		return netip.Addr{}, false
+	}
+
+	if addr, err := netip.ParseAddr(hop); err == nil {
+		return addr.Unmap(), true
+	}
+	if addrPort, err := netip.ParseAddrPort(hop); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	if addr, err := netip.ParseAddr(strings.Trim(hop, "[]")); err == nil {
+		return addr.Unmap(), true
+	}
+	return netip.Addr{}, false
+}
+
+// parseForwarded extracts the for= values of an RFC 7239 Forwarded header
+func parseForwarded(value string) []string {
+	var chain []string
+	for _, element := range strings.Split(value, ",") {
+		for _, pair := range strings.Split(element, ";") {
+			key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if ok && strings.EqualFold(key, "for") {
+				chain = append(chain, val)
+			}
+		}
+	}
+	return chain
+}
+
+// ClientIP stores the resolved client address in locals for the rate
+// limiter and request logging
+func ClientIP(resolver *ClientIPResolver) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		header := func(name string) string { return c.Get(name) }
+		c.Locals("client_ip", resolver.Resolve(c.Context().RemoteIP().String(), header))
+		return c.Next()
+	}
+}
+
+// GetClientIP returns the address resolved by ClientIP, or the peer address
+// when the middleware is not installed
+func GetClientIP(c *fiber.Ctx) string {
+	if ip, ok := c.Locals("client_ip").(string); ok && ip != "" {
+		return ip
+	}
+	return c.IP()
+}
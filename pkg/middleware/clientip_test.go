@@ -0,0 +1,102 @@
+package middleware
+
+import "testing"
+
+func TestClientIPResolver(t *testing.T) {
+	resolver, err := NewClientIPResolver([]string{"10.0.0.0/8", "192.168.1.1", "fd00::/8"}, nil)
+	if err != nil {
+		t.Fatalf("NewClientIPResolver: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		{
+			name:   "untrusted peer ignores headers",
+			remote: "203.0.113.7",
+			headers: map[string]string{
+				"X-Forwarded-For": "1.1.1.1",
+				"X-Real-IP":       "1.1.1.1",
+			},
+			want: "203.0.113.7",
+		},
+		{
+			name:    "trusted peer without headers",
+			remote:  "10.0.0.2",
+			headers: nil,
+			want:    "10.0.0.2",
+		},
+		{
+			name:    "x-forwarded-for single hop",
+			remote:  "10.0.0.2",
+			headers: map[string]string{"X-Forwarded-For": "198.51.100.4"},
+			want:    "198.51.100.4",
+		},
+		{
+			name:    "x-forwarded-for skips trusted hops from the right",
+			remote:  "10.0.0.2",
+			headers: map[string]string{"X-Forwarded-For": "6.6.6.6, 198.51.100.4, 10.1.2.3, 192.168.1.1"},
+			want:    "198.51.100.4",
+		},
+		{
+			name:    "x-forwarded-for all trusted returns leftmost",
+			remote:  "10.0.0.2",
+			headers: map[string]string{"X-Forwarded-For": "10.9.9.9, 10.1.2.3"},
+			want:    "10.9.9.9",
+		},
+		{
+			name:    "forwarded header with ports and ipv6",
+			remote:  "10.0.0.2",
+			headers: map[string]string{"Forwarded": `for=192.0.2.60;proto=http, for="[2001:db8:cafe::17]:4711";by=10.0.0.1`},
+			want:    "2001:db8:cafe::17",
+		},
+		{
+			name:    "forwarded wins over x-forwarded-for",
+			remote:  "10.0.0.2",
+			headers: map[string]string{"Forwarded": "for=192.0.2.60", "X-Forwarded-For": "198.51.100.4"},
+			want:    "192.0.2.60",
+		},
+		{
+			name:    "obfuscated forwarded falls back to next header",
+			remote:  "10.0.0.2",
+			headers: map[string]string{"Forwarded": "for=_hidden", "X-Real-IP": "198.51.100.9"},
+			want:    "198.51.100.9",
+		},
+		{
+			name:    "x-real-ip",
+			remote:  "fd00::1",
+			headers: map[string]string{"X-Real-IP": "198.51.100.9"},
+			want:    "198.51.100.9",
+		},
+		{
+			name:    "ipv4 mapped peer",
+			remote:  "::ffff:10.0.0.2",
+			headers: map[string]string{"X-Forwarded-For": "198.51.100.4:5555"},
+			want:    "198.51.100.4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolver.Resolve(tt.remote, func(name string) string { return tt.headers[name] })
+			if got != tt.want {
+				t.Errorf("Resolve = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewClientIPResolverRejectsInvalidConfig(t *testing.T) {
+	if _, err := NewClientIPResolver([]string{"10.0.0.0/33"}, nil); err == nil {
+		t.Error("expected error for invalid CIDR")
+	}
+	if _, err := NewClientIPResolver([]string{"proxy.local"}, nil); err == nil {
+		t.Error("expected error for hostname")
+	}
+	if _, err := NewClientIPResolver(nil, []string{"X-Client-IP"}); err == nil {
+		t.Error("expected error for unsupported header")
+	}
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+)
+
+var ErrInsecureCORS = errors.New("cors: credentials cannot be allowed for the wildcard origin")
+
+type CORSConfig struct {
+	// AllowOrigins lists exact origins such as https://app.example.com and
+	// subdomain patterns such as https://*.example.com; "*" allows any origin
+	AllowOrigins     []string
+	AllowMethods     []string
+	AllowHeaders     []string
+	ExposeHeaders    []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// originPattern matches one configured origin; a wildcard pattern matches any
+// subdomain of host, but not host itself
+type originPattern struct {
+	scheme   string
+	host     string
+	wildcard bool
+}
+
+// CORS validates the policy up front instead of letting a bad origin panic at
+// startup, and matches origins itself so *.example.com cannot be satisfied by
+// evilexample.com. With no origins configured every cross-origin request is
+// refused
+func CORS(cfg CORSConfig) (fiber.Handler, error) {
+	config := cors.Config{
+		AllowMethods:     strings.Join(cfg.AllowMethods, ","),
+		AllowHeaders:     strings.Join(cfg.AllowHeaders, ","),
+		ExposeHeaders:    strings.Join(cfg.ExposeHeaders, ","),
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           int(cfg.MaxAge / time.Second),
+	}
+
+	if len(cfg.AllowOrigins) == 1 && cfg.AllowOrigins[0] == "*" {
+		if cfg.AllowCredentials {
+			return nil, ErrInsecureCORS
+		}
+		config.AllowOrigins = "*"
+		return cors.New(config), nil
+	}
+
+	patterns := make([]originPattern, 0, len(cfg.AllowOrigins))
+	for _, origin := range cfg.AllowOrigins {
+		pattern, err := parseOriginPattern(origin)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	config.AllowOriginsFunc = func(origin string) bool {
+		u, err := url.Parse(origin)
+		if err != nil {
+			return false
+		}
+		for _, p := range patterns {
+			if p.matches(strings.ToLower(u.Scheme), strings.ToLower(u.Host)) {
+				return true
+			}
+		}
+		return false
+	}
+	return cors.New(config), nil
+}
+
+func parseOriginPattern(origin string) (originPattern, error) {
+	if origin == "*" {
+		return originPattern{}, fmt.Errorf("cors: %q must be the only origin", origin)
+	}
+
+	wildcard := strings.Contains(origin, "://*.")
+	u, err := url.Parse(strings.Replace(origin, "://*.", "://", 1))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		strings.Contains(u.Host, "*") || strings.Trim(u.Path, "/") != "" || u.RawQuery != "" || u.User != nil {
+		return originPattern{}, fmt.Errorf("cors: invalid origin %q, want scheme://host[:port] or scheme://*.host", origin)
+	}
+
+	return originPattern{
+		scheme:   strings.ToLower(u.Scheme),
+		host:     strings.ToLower(u.Host),
+		wildcard: wildcard,
+	}, nil
+}
+
+func (p originPattern) matches(scheme, host string) bool {
+	if scheme != p.scheme {
+		return false
+	}
+	if p.wildcard {
+		return strings.HasSuffix(host, "."+p.host)
+	}
+	return host == p.host
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCORSOrigins(t *testing.T) {
+	handler, err := CORS(CORSConfig{
+		AllowOrigins:     []string{"https://app.example.com", "https://*.gallery.test"},
+		AllowMethods:     []string{"GET", "POST"},
+		AllowHeaders:     []string{"Authorization", "X-Tenant-ID"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("CORS: %v", err)
+	}
+
+	app := fiber.New()
+	app.Use(handler)
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{origin: "https://app.example.com", allowed: true},
+		{origin: "https://eu.gallery.test", allowed: true},
+		{origin: "https://a.b.gallery.test", allowed: true},
+		// The wildcard only covers subdomains
+		{origin: "https://gallery.test", allowed: false},
+		{origin: "https://evilgallery.test", allowed: false},
+		{origin: "http://eu.gallery.test", allowed: false},
+		{origin: "https://app.example.com.evil.test", allowed: false},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(fiber.MethodOptions, "/", nil)
+		req.Header.Set(fiber.HeaderOrigin, tt.origin)
+		req.Header.Set(fiber.HeaderAccessControlRequestMethod, fiber.MethodPost)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test(%s): %v", tt.origin, err)
+		}
+
+		got := resp.Header.Get(fiber.HeaderAccessControlAllowOrigin)
+		if tt.allowed && got != tt.origin {
+			t.Errorf("%s: Access-Control-Allow-Origin = %q, want origin echoed", tt.origin, got)
+		}
+		if !tt.allowed && got != "" {
+			t.Errorf("%s: Access-Control-Allow-Origin = %q, want none", tt.origin, got)
+		}
+		if tt.allowed && resp.Header.Get(fiber.HeaderAccessControlAllowCredentials) != "true" {
+			t.Errorf("%s: credentials not allowed", tt.origin)
+		}
+		if tt.allowed && resp.Header.Get(fiber.HeaderAccessControlMaxAge) != "3600" {
+			t.Errorf("%s: Access-Control-Max-Age = %q", tt.origin, resp.Header.Get(fiber.HeaderAccessControlMaxAge))
+		}
+	}
+}
+
+func TestCORSNoOriginsRefusesAll(t *testing.T) {
+	handler, err := CORS(CORSConfig{})
+	if err != nil {
+		t.Fatalf("CORS: %v", err)
+	}
+
+	app := fiber.New()
+	app.Use(handler)
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	req := httptest.NewRequest(fiber.MethodGet, "/", nil)
+	req.Header.Set(fiber.HeaderOrigin, "https://app.example.com")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	if got := resp.Header.Get(fiber.HeaderAccessControlAllowOrigin); got != "" {
+		t.Fatalf("Access-Control-Allow-Origin = %q, want none", got)
+	}
+}
+
+func TestCORSRejectsInvalidConfig(t *testing.T) {
+	if _, err := CORS(CORSConfig{AllowOrigins: []string{"*"}, AllowCredentials: true}); !errors.Is(err, ErrInsecureCORS) {
+		t.Errorf("err = %v, want %v", err, ErrInsecureCORS)
+	}
+
+	for _, origin := range []string{"app.example.com", "ftp://example.com", "https://example.com/path", "https://ex*ample.com", "*"} {
+		if _, err := CORS(CORSConfig{AllowOrigins: []string{"https://ok.test", origin}}); err == nil {
+			t.Errorf("origin %q: expected error", origin)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"pre-test-gallery-service/pkg/i18n"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Language negotiates the response language from Accept-Language and stores
+// it in locals, falling back to defaultLanguage
+func Language(defaultLanguage string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Locals("language", i18n.Negotiate(c.Get(fiber.HeaderAcceptLanguage), defaultLanguage))
+		c.Vary(fiber.HeaderAcceptLanguage)
+		return c.Next()
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"pre-test-gallery-service/pkg/metrics"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Metrics records request counts and latencies labelled by route template,
+// and the bytes received by multipart uploads
+func Metrics() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		nextAndRenderError(c)
+
+		route := c.Route().Path
+		status := strconv.Itoa(c.Response().StatusCode())
+
+		metrics.HTTPRequestsTotal.WithLabelValues(c.Method(), route, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(c.Method(), route, status).Observe(time.Since(start).Seconds())
+
+		if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+			metrics.UploadBytesTotal.WithLabelValues(route).Add(float64(bodySize(c)))
+		}
+
+		return nil
+	}
+}
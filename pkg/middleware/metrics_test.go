@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"pre-test-gallery-service/pkg/metrics"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	app := fiber.New()
+	app.Use(Metrics())
+	app.Post("/metrics-test/:id", RateLimit(1, time.Minute), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusCreated)
+	})
+
+	requests := testutil.ToFloat64(metrics.HTTPRequestsTotal.WithLabelValues(fiber.MethodPost, "/metrics-test/:id", "201"))
+	rejections := testutil.ToFloat64(metrics.RateLimitRejectionsTotal.WithLabelValues("default"))
+	uploaded := testutil.ToFloat64(metrics.UploadBytesTotal.WithLabelValues("/metrics-test/:id"))
+
+	for _, id := range []string{"1", "2"} {
+		body := "--b\r\nContent-Disposition: form-data; name=\"f\"\r\n\r\ndata\r\n--b--\r\n"
+		req := httptest.NewRequest(fiber.MethodPost, "/metrics-test/"+id, strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEMultipartForm+"; boundary=b")
+		if _, err := app.Test(req); err != nil {
+			t.Fatalf("app.Test: %v", err)
+		}
+	}
+
+	if got := testutil.ToFloat64(metrics.HTTPRequestsTotal.WithLabelValues(fiber.MethodPost, "/metrics-test/:id", "201")) - requests; got != 1 {
+		t.Errorf("requests with status 201 = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.RateLimitRejectionsTotal.WithLabelValues("default")) - rejections; got != 1 {
+		t.Errorf("rate limit rejections = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.UploadBytesTotal.WithLabelValues("/metrics-test/:id")) - uploaded; got <= 0 {
+		t.Errorf("upload bytes = %v, want > 0", got)
+	}
+}
@@ -1,74 +1,191 @@
 package middleware
 
 import (
-	"net/http"
+	"hash/maphash"
+	"pre-test-gallery-service/pkg/apperror"
+	"pre-test-gallery-service/pkg/logger"
+	"pre-test-gallery-service/pkg/metrics"
 	"pre-test-gallery-service/pkg/utils"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+const limiterShards = 64
+
+// RateLimiter is a token bucket limiter: every key may burst up to rate
+// requests and regains rate tokens per interval. Keys are spread over
+// sharded maps so unrelated clients do not contend on one lock, and buckets
+// that have refilled completely are evicted because they are
+// indistinguishable from a key that was never seen.
 type RateLimiter struct {
 	rate     int
 	interval time.Duration
-	mu       sync.Mutex
-	tokens   map[string][]time.Time
+	// refill is the time it takes to regain a single token
+	refill time.Duration
+	seed   maphash.Seed
+	shards [limiterShards]limiterShard
+	now    func() time.Time
+}
+
+type limiterShard struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep int64
+}
+
+// bucket is kept small so memory per key stays constant
+type bucket struct {
+	tokens float64
+	last   int64
+}
+
+// RateLimitResult describes the state of a key after a call to Take
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long to wait before the next request is allowed
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
 }
 
+// NewRateLimiter rejects every request when rate is not positive
 func NewRateLimiter(rate int, interval time.Duration) *RateLimiter {
-	return &RateLimiter{
+	rate = max(rate, 0)
+	refill := interval
+	if rate > 0 {
+		refill = interval / time.Duration(rate)
+	}
+	if refill <= 0 {
+		refill = 1
+	}
+
+	rl := &RateLimiter{
 		rate:     rate,
 		interval: interval,
-		tokens:   make(map[string][]time.Time),
+		refill:   refill,
+		seed:     maphash.MakeSeed(),
+		now:      time.Now,
 	}
+	for i := range rl.shards {
+		rl.shards[i].buckets = make(map[string]*bucket)
+	}
+	return rl
 }
 
 func (rl *RateLimiter) Allow(key string) bool {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
+	return rl.Take(key).Allowed
+}
 
-	now := time.Now()
-	windowStart := now.Add(-rl.interval)
+// Take consumes a token for key if one is available
+func (rl *RateLimiter) Take(key string) RateLimitResult {
+	now := rl.now().UnixNano()
+	shard := &rl.shards[maphash.String(rl.seed, key)%limiterShards]
 
-	if _, exists := rl.tokens[key]; !exists {
-		rl.tokens[key] = []time.Time{now}
-		return true
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if now-shard.lastSweep >= int64(rl.interval) {
+		rl.sweep(shard, now)
 	}
 
-	var validTokens []time.Time
-	for _, t := range rl.tokens[key] {
-		if t.After(windowStart) {
-			validTokens = append(validTokens, t)
-		}
+	b, exists := shard.buckets[key]
+	if !exists {
+		b = &bucket{tokens: float64(rl.rate), last: now}
+		shard.buckets[key] = b
+	} else {
+		b.tokens = rl.refilled(b, now)
+		b.last = now
+	}
+
+	result := RateLimitResult{Limit: rl.rate}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) * float64(rl.refill))
 	}
 
-	if len(validTokens) < rl.rate {
-		validTokens = append(validTokens, now)
-		rl.tokens[key] = validTokens
-		return true
+	result.Remaining = int(b.tokens)
+	result.Reset = time.Duration((float64(rl.rate) - b.tokens) * float64(rl.refill))
+	return result
+}
+
+// Len returns the number of keys currently tracked
+func (rl *RateLimiter) Len() int {
+	n := 0
+	for i := range rl.shards {
+		shard := &rl.shards[i]
+		shard.mu.Lock()
+		n += len(shard.buckets)
+		shard.mu.Unlock()
+	}
+	return n
+}
+
+func (rl *RateLimiter) refilled(b *bucket, now int64) float64 {
+	tokens := b.tokens + float64(now-b.last)/float64(rl.refill)
+	if tokens > float64(rl.rate) {
+		return float64(rl.rate)
 	}
+	return tokens
+}
 
-	rl.tokens[key] = validTokens
-	return false
+// sweep evicts buckets that are full again; the caller holds shard.mu
+func (rl *RateLimiter) sweep(shard *limiterShard, now int64) {
+	for key, b := range shard.buckets {
+		if rl.refilled(b, now) >= float64(rl.rate) {
+			delete(shard.buckets, key)
+		}
+	}
+	shard.lastSweep = now
 }
 
 func RateLimit(rate int, interval time.Duration) fiber.Handler {
-	limiter := NewRateLimiter(rate, interval)
+	return RateLimitWithStore("default", NewMemoryStore(rate, interval))
+}
 
+// RateLimitWithStore limits each client identity and reports the state of
+// its bucket in the RateLimit-* headers; policy labels rejection metrics
+func RateLimitWithStore(policy string, store RateLimitStore) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		// use ip
-		key := c.IP()
+		res, err := store.Take(c.UserContext(), RateLimitKey(c))
+		if err != nil {
+			// Fail open so an outage of a shared store does not take the API down
+			logger.FromContext(c.UserContext()).Error("Rate limit store error", "error", err)
+			return c.Next()
+		}
 
-		// or use user id on jwt
-		// if user, ok := c.Locals("user").(string); ok {
-		// 		key = user
-		// }
+		c.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
 
-		if !limiter.Allow(key) {
-			return utils.SendError(c, http.StatusTooManyRequests, "Rate limit exceeded. Please try again later.")
+		if !res.Allowed {
+			metrics.RateLimitRejectionsTotal.WithLabelValues(policy).Inc()
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			return utils.SendError(c, apperror.ErrRateLimited)
 		}
 
 		return c.Next()
 	}
 }
+
+// RateLimitKey identifies the client: the API key, then the authenticated
+// user, and only for anonymous requests the IP address
+func RateLimitKey(c *fiber.Ctx) string {
+	if apiKey, ok := c.Locals("api_key").(string); ok && apiKey != "" {
+		return "apikey:" + apiKey
+	}
+	if user, ok := c.Locals("user").(string); ok && user != "" {
+		return "user:" + user
+	}
+	return "ip:" + GetClientIP(c)
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RateLimitPolicy allows Rate requests per Interval
+type RateLimitPolicy struct {
+	Rate     int
+	Interval time.Duration
+}
+
+// RateLimitPolicies holds the default policy and overrides per route name
+type RateLimitPolicies struct {
+	Default RateLimitPolicy
+	Routes  map[string]RateLimitPolicy
+}
+
+var DefaultRateLimitPolicy = RateLimitPolicy{Rate: 100, Interval: time.Minute}
+
+// ParseRateLimitPolicy parses "100/1m" (rate per duration)
+func ParseRateLimitPolicy(raw string) (RateLimitPolicy, error) {
+	rate, interval, ok := strings.Cut(strings.TrimSpace(raw), "/")
+	if !ok {
+		return RateLimitPolicy{}, fmt.Errorf("invalid rate limit %q, expected <rate>/<duration>", raw)
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(rate))
+	if err != nil || n <= 0 {
+		return RateLimitPolicy{}, fmt.Errorf("invalid rate in rate limit %q", raw)
+	}
+
+	d, err := time.ParseDuration(strings.TrimSpace(interval))
+	if err != nil || d <= 0 {
+		return RateLimitPolicy{}, fmt.Errorf("invalid duration in rate limit %q", raw)
+	}
+
+	return RateLimitPolicy{Rate: n, Interval: d}, nil
+}
+
+// ParseRateLimitPolicies parses the default policy and a list of route
+// overrides such as "tags.create=10/1m,auth.login=5/1m"
+func ParseRateLimitPolicies(defaultRaw, routesRaw string) (*RateLimitPolicies, error) {
+	policies := &RateLimitPolicies{
+		Default: DefaultRateLimitPolicy,
+		Routes:  make(map[string]RateLimitPolicy),
+	}
+
+	if strings.TrimSpace(defaultRaw) != "" {
+		policy, err := ParseRateLimitPolicy(defaultRaw)
+		if err != nil {
+			return nil, err
+		}
+		policies.Default = policy
+	}
+
+	for _, entry := range strings.Split(routesRaw, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		route, raw, ok := strings.Cut(entry, "=")
+		route = strings.TrimSpace(route)
+		if !ok || route == "" {
+			return nil, fmt.Errorf("invalid rate limit policy %q, expected <route>=<rate>/<duration>", entry)
+		}
+
+		policy, err := ParseRateLimitPolicy(raw)
+		if err != nil {
+			return nil, err
+		}
+		policies.Routes[route] = policy
+	}
+
+	return policies, nil
+}
+
+// RateLimiters hands out the limiter of each named route. Routes with their
+// own policy get their own bucket; all other routes share the default one.
+type RateLimiters struct {
+	policies *RateLimitPolicies
+	stores   RateLimitStoreFactory
+	shared   fiber.Handler
+}
+
+func NewRateLimiters(policies *RateLimitPolicies, stores RateLimitStoreFactory) *RateLimiters {
+	return &RateLimiters{
+		policies: policies,
+		stores:   stores,
+		shared:   RateLimitWithStore("default", stores("default", policies.Default.Rate, policies.Default.Interval)),
+	}
+}
+
+func (r *RateLimiters) For(route string) fiber.Handler {
+	policy, ok := r.policies.Routes[route]
+	if !ok {
+		return r.shared
+	}
+	return RateLimitWithStore(route, r.stores(route, policy.Rate, policy.Interval))
+}
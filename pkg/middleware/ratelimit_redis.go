@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// gcraScript implements the generic cell rate algorithm, which is equivalent
+// to a token bucket but needs a single timestamp per key. The Redis server
+// clock is used so replicas with skewed clocks still agree.
+//
+// KEYS[1] bucket key, ARGV[1] rate, ARGV[2] interval in microseconds.
+// Returns {allowed, remaining, reset_us, retry_after_us}.
+var gcraScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local emission = period / rate
+
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+
+local tat = tonumber(redis.call('GET', KEYS[1]))
+if not tat or tat < now then
+	tat = now
+end
+
+local new_tat = tat + emission
+local allow_at = new_tat - period
+if allow_at > now then
+	return {0, 0, math.ceil(tat - now), math.ceil(allow_at - now)}
+end
+
+local ttl = math.ceil((new_tat - now) / 1000)
+if ttl < 1 then
+	ttl = 1
+end
+redis.call('SET', KEYS[1], string.format('%d', new_tat), 'PX', ttl)
+
+local remaining = math.floor((period - (new_tat - now)) / emission)
+return {1, remaining, math.ceil(new_tat - now), 0}
+`)
+
+type redisStore struct {
+	client   redis.Scripter
+	prefix   string
+	rate     int
+	interval time.Duration
+}
+
+// NewRedisStore enforces the policy cluster-wide; keys are stored under
+// prefix and expire once their bucket would be full again. A rate that is
+// not positive rejects every request without asking Redis
+func NewRedisStore(client redis.Scripter, prefix string, rate int, interval time.Duration) RateLimitStore {
+	return &redisStore{
+		client:   client,
+		prefix:   prefix,
+		rate:     rate,
+		interval: interval,
+	}
+}
+
+func (s *redisStore) Take(ctx context.Context, key string) (RateLimitResult, error) {
+	if s.rate <= 0 {
+		return RateLimitResult{RetryAfter: s.interval}, nil
+	}
+
+	values, err := gcraScript.Run(ctx, s.client, []string{s.prefix + key}, s.rate, s.interval.Microseconds()).Int64Slice()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	return RateLimitResult{
+		Allowed:    values[0] == 1,
+		Limit:      s.rate,
+		Remaining:  int(values[1]),
+		Reset:      time.Duration(values[2]) * time.Microsecond,
+		RetryAfter: time.Duration(values[3]) * time.Microsecond,
+	}, nil
+}
+
+// RedisStoreFactory namespaces each policy under "ratelimit:<name>:"
+func RedisStoreFactory(client redis.Scripter) RateLimitStoreFactory {
+	return func(name string, rate int, interval time.Duration) RateLimitStore {
+		return NewRedisStore(client, "ratelimit:"+name+":", rate, interval)
+	}
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return server, client
+}
+
+func TestRedisStoreSharedAcrossReplicas(t *testing.T) {
+	server, client := newTestRedis(t)
+	now := time.Unix(1700000000, 0)
+	server.SetTime(now)
+
+	// Two stores with the same prefix behave like two replicas of the API
+	factory := RedisStoreFactory(client)
+	replicaA := factory("api", 3, 3*time.Second)
+	replicaB := factory("api", 3, 3*time.Second)
+	ctx := context.Background()
+
+	for i, store := range []RateLimitStore{replicaA, replicaB, replicaA} {
+		res, err := store.Take(ctx, "10.0.0.1")
+		if err != nil {
+			t.Fatalf("Take: %v", err)
+		}
+		if !res.Allowed || res.Remaining != 2-i {
+			t.Fatalf("request %d: got %+v", i, res)
+		}
+	}
+
+	res, err := replicaB.Take(ctx, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+	if res.Allowed {
+		t.Fatal("expected the shared limit to reject the fourth request")
+	}
+	if res.RetryAfter != time.Second {
+		t.Fatalf("RetryAfter = %v, want %v", res.RetryAfter, time.Second)
+	}
+	if res.Reset != 3*time.Second {
+		t.Fatalf("Reset = %v, want %v", res.Reset, 3*time.Second)
+	}
+
+	// Other policies and keys are independent
+	if res, _ := factory("auth", 3, 3*time.Second).Take(ctx, "10.0.0.1"); !res.Allowed {
+		t.Fatal("expected another policy to have its own bucket")
+	}
+	if res, _ := replicaA.Take(ctx, "10.0.0.2"); !res.Allowed {
+		t.Fatal("expected another key to have its own bucket")
+	}
+
+	server.SetTime(now.Add(time.Second))
+	if res, _ := replicaA.Take(ctx, "10.0.0.1"); !res.Allowed {
+		t.Fatal("expected a token to be refilled after one second")
+	}
+	if res, _ := replicaB.Take(ctx, "10.0.0.1"); res.Allowed {
+		t.Fatal("expected only one refilled token")
+	}
+}
+
+func TestRedisStoreExpiresIdleKeys(t *testing.T) {
+	server, client := newTestRedis(t)
+	store := NewRedisStore(client, "ratelimit:api:", 10, time.Minute)
+
+	if _, err := store.Take(context.Background(), "10.0.0.1"); err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+	if !server.Exists("ratelimit:api:10.0.0.1") {
+		t.Fatal("expected bucket key to be stored")
+	}
+
+	server.FastForward(time.Minute)
+	if server.Exists("ratelimit:api:10.0.0.1") {
+		t.Fatal("expected bucket key to expire once refilled")
+	}
+}
+
+func TestRedisStoreError(t *testing.T) {
+	server, client := newTestRedis(t)
+	server.Close()
+
+	if _, err := NewRedisStore(client, "ratelimit:api:", 10, time.Minute).Take(context.Background(), "k"); err == nil {
+		t.Fatal("expected error when redis is unavailable")
+	}
+}
+
+func TestRedisStoreWithoutRate(t *testing.T) {
+	server, client := newTestRedis(t)
+	store := NewRedisStore(client, "ratelimit:api:", 0, time.Minute)
+
+	res, err := store.Take(context.Background(), "10.0.0.1")
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+	if res.Allowed || res.RetryAfter != time.Minute {
+		t.Fatalf("got %+v, want the request rejected", res)
+	}
+	if server.Exists("ratelimit:api:10.0.0.1") {
+		t.Fatal("expected no bucket key for a policy that rejects everything")
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"context"
+	"time"
+)
+
+// RateLimitStore holds the buckets of one rate limit policy. The in-memory
+// store limits per process; the Redis store shares the limit across replicas.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string) (RateLimitResult, error)
+}
+
+// RateLimitStoreFactory creates the store of a named policy
+type RateLimitStoreFactory func(name string, rate int, interval time.Duration) RateLimitStore
+
+type memoryStore struct {
+	limiter *RateLimiter
+}
+
+func NewMemoryStore(rate int, interval time.Duration) RateLimitStore {
+	return &memoryStore{limiter: NewRateLimiter(rate, interval)}
+}
+
+func (s *memoryStore) Take(ctx context.Context, key string) (RateLimitResult, error) {
+	return s.limiter.Take(key), nil
+}
+
+func MemoryStoreFactory(name string, rate int, interval time.Duration) RateLimitStore {
+	return NewMemoryStore(rate, interval)
+}
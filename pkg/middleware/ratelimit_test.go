@@ -0,0 +1,303 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"pre-test-gallery-service/pkg/auth"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	return f.now
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.now = f.now.Add(d)
+}
+
+func newTestLimiter(rate int, interval time.Duration) (*RateLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	rl := NewRateLimiter(rate, interval)
+	rl.now = clock.Now
+	return rl, clock
+}
+
+func TestRateLimiterWithoutRate(t *testing.T) {
+	for _, rate := range []int{0, -1} {
+		rl, clock := newTestLimiter(rate, time.Minute)
+
+		for i := 0; i < 2; i++ {
+			res := rl.Take("a")
+			if res.Allowed || res.Limit != 0 || res.Remaining != 0 {
+				t.Fatalf("rate %d: got %+v, want every request rejected", rate, res)
+			}
+			if res.RetryAfter != time.Minute {
+				t.Fatalf("rate %d: RetryAfter = %v, want the interval", rate, res.RetryAfter)
+			}
+			clock.Advance(time.Hour)
+		}
+	}
+}
+
+func TestRateLimiterBurstAndRefill(t *testing.T) {
+	rl, clock := newTestLimiter(3, 3*time.Second)
+
+	for i := 0; i < 3; i++ {
+		if res := rl.Take("a"); !res.Allowed || res.Remaining != 2-i {
+			t.Fatalf("request %d: got %+v", i, res)
+		}
+	}
+
+	res := rl.Take("a")
+	if res.Allowed {
+		t.Fatal("expected fourth request to be rejected")
+	}
+	if res.RetryAfter != time.Second {
+		t.Fatalf("RetryAfter = %v, want %v", res.RetryAfter, time.Second)
+	}
+
+	// Other keys have their own bucket
+	if !rl.Allow("b") {
+		t.Fatal("expected other key to be allowed")
+	}
+
+	clock.Advance(time.Second)
+	if !rl.Allow("a") {
+		t.Fatal("expected a token to be refilled after one second")
+	}
+	if rl.Allow("a") {
+		t.Fatal("expected only one refilled token")
+	}
+}
+
+func TestRateLimiterEvictsIdleKeys(t *testing.T) {
+	rl, clock := newTestLimiter(10, time.Minute)
+
+	for i := 0; i < 1000; i++ {
+		rl.Allow("ip-" + strconv.Itoa(i))
+	}
+	if got := rl.Len(); got != 1000 {
+		t.Fatalf("Len = %d, want 1000", got)
+	}
+
+	clock.Advance(2 * time.Minute)
+	for i := 0; i < limiterShards*20; i++ {
+		rl.Allow("fresh-" + strconv.Itoa(i))
+	}
+
+	if got := rl.Len(); got != limiterShards*20 {
+		t.Fatalf("Len = %d after eviction, want %d", got, limiterShards*20)
+	}
+}
+
+func TestRateLimiterKeepsLimitedKeys(t *testing.T) {
+	rl, clock := newTestLimiter(2, time.Minute)
+
+	rl.Allow("a")
+	rl.Allow("a")
+	clock.Advance(time.Minute / 2)
+	// Force a sweep on every shard; "a" is not full yet and must survive it
+	for i := 0; i < limiterShards*20; i++ {
+		rl.Allow("other-" + strconv.Itoa(i))
+	}
+	clock.Advance(time.Minute / 2)
+	for i := 0; i < limiterShards*20; i++ {
+		rl.Allow("more-" + strconv.Itoa(i))
+	}
+
+	if rl.Allow("a") && rl.Allow("a") && rl.Allow("a") {
+		t.Fatal("limit for \"a\" was reset by eviction")
+	}
+}
+
+func BenchmarkRateLimiterSingleKey(b *testing.B) {
+	rl := NewRateLimiter(100, time.Minute)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		rl.Allow("127.0.0.1")
+	}
+}
+
+func BenchmarkRateLimiterHighCardinalityParallel(b *testing.B) {
+	rl := NewRateLimiter(100, time.Minute)
+	var counter atomic.Uint64
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			rl.Allow(strconv.FormatUint(counter.Add(1), 10))
+		}
+	})
+}
+
+// BenchmarkRateLimiterMemoryPerKey reports the heap used per tracked key,
+// which stays flat as the number of keys grows
+func BenchmarkRateLimiterMemoryPerKey(b *testing.B) {
+	for _, keys := range []int{10_000, 100_000, 1_000_000} {
+		b.Run(strconv.Itoa(keys), func(b *testing.B) {
+			names := make([]string, keys)
+			for i := range names {
+				names[i] = "10.0." + strconv.Itoa(i/65536) + "." + strconv.Itoa(i%65536)
+			}
+
+			for n := 0; n < b.N; n++ {
+				var before, after runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+
+				rl := NewRateLimiter(100, time.Minute)
+				for _, name := range names {
+					rl.Allow(name)
+				}
+
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				b.ReportMetric(float64(after.HeapAlloc-before.HeapAlloc)/float64(keys), "bytes/key")
+				runtime.KeepAlive(rl)
+			}
+		})
+	}
+}
+
+func TestParseRateLimitPolicies(t *testing.T) {
+	policies, err := ParseRateLimitPolicies("50/30s", " tags.create=5/1m , auth.login=1/1h")
+	if err != nil {
+		t.Fatalf("ParseRateLimitPolicies: %v", err)
+	}
+	if policies.Default != (RateLimitPolicy{Rate: 50, Interval: 30 * time.Second}) {
+		t.Errorf("Default = %+v", policies.Default)
+	}
+	if policies.Routes["tags.create"] != (RateLimitPolicy{Rate: 5, Interval: time.Minute}) {
+		t.Errorf("tags.create = %+v", policies.Routes["tags.create"])
+	}
+	if policies.Routes["auth.login"] != (RateLimitPolicy{Rate: 1, Interval: time.Hour}) {
+		t.Errorf("auth.login = %+v", policies.Routes["auth.login"])
+	}
+
+	defaults, err := ParseRateLimitPolicies("", "")
+	if err != nil || defaults.Default != DefaultRateLimitPolicy {
+		t.Errorf("empty config = %+v, %v", defaults, err)
+	}
+
+	for _, raw := range []string{"tags.create", "tags.create=5", "tags.create=x/1m", "tags.create=5/soon", "=5/1m", "tags.create=0/1m"} {
+		if _, err := ParseRateLimitPolicies("", raw); err == nil {
+			t.Errorf("ParseRateLimitPolicies(%q) expected error", raw)
+		}
+	}
+}
+
+func TestRateLimitHeadersAndIdentity(t *testing.T) {
+	policies := &RateLimitPolicies{
+		Default: RateLimitPolicy{Rate: 2, Interval: time.Minute},
+		Routes:  map[string]RateLimitPolicy{"strict": {Rate: 1, Interval: time.Minute}},
+	}
+	limits := NewRateLimiters(policies, MemoryStoreFactory)
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		if user := c.Get("X-Test-User"); user != "" {
+			c.Locals("user", user)
+		}
+		return c.Next()
+	})
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+	app.Get("/a", limits.For("a"), ok)
+	app.Get("/b", limits.For("b"), ok)
+	app.Get("/strict", limits.For("strict"), ok)
+
+	do := func(path, user string) *http.Response {
+		req := httptest.NewRequest(fiber.MethodGet, path, nil)
+		if user != "" {
+			req.Header.Set("X-Test-User", user)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test: %v", err)
+		}
+		return resp
+	}
+
+	resp := do("/a", "alice")
+	if resp.StatusCode != fiber.StatusOK || resp.Header.Get("RateLimit-Limit") != "2" || resp.Header.Get("RateLimit-Remaining") != "1" {
+		t.Fatalf("first request: %d %v", resp.StatusCode, resp.Header)
+	}
+	if resp.Header.Get("RateLimit-Reset") != "30" {
+		t.Errorf("RateLimit-Reset = %q, want 30", resp.Header.Get("RateLimit-Reset"))
+	}
+
+	// Routes without a policy share the default bucket
+	if resp := do("/b", "alice"); resp.StatusCode != fiber.StatusOK || resp.Header.Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("second request: %d %v", resp.StatusCode, resp.Header)
+	}
+	resp = do("/a", "alice")
+	if resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Fatalf("third request status = %d, want 429", resp.StatusCode)
+	}
+	if resp.Header.Get("Retry-After") != "30" {
+		t.Errorf("Retry-After = %q, want 30", resp.Header.Get("Retry-After"))
+	}
+
+	// Another user from the same IP has its own bucket
+	if resp := do("/a", "bob"); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("other user status = %d, want 200", resp.StatusCode)
+	}
+
+	// A route policy has its own bucket and limit
+	if resp := do("/strict", "alice"); resp.StatusCode != fiber.StatusOK || resp.Header.Get("RateLimit-Limit") != "1" {
+		t.Fatalf("strict route: %d %v", resp.StatusCode, resp.Header)
+	}
+	if resp := do("/strict", "alice"); resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Fatalf("strict route status = %d, want 429", resp.StatusCode)
+	}
+}
+
+func TestRateLimitKeyFromToken(t *testing.T) {
+	issuer := auth.NewTokenIssuer("secret", time.Hour)
+	token := func(subject string) string {
+		t.Helper()
+		signed, _, err := issuer.Issue(&auth.Identity{Subject: subject, Role: auth.RoleEditor})
+		if err != nil {
+			t.Fatalf("Issue: %v", err)
+		}
+		return signed
+	}
+
+	app := fiber.New()
+	app.Use(Authenticate(issuer))
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString(RateLimitKey(c)) })
+
+	tests := []struct {
+		name, token, want string
+	}{
+		{name: "api key", token: token(auth.APIKeySubjectPrefix + "ci"), want: "apikey:ci"},
+		{name: "user", token: token("alice"), want: "user:alice"},
+		{name: "anonymous", want: "ip:0.0.0.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodGet, "/", nil)
+			if tt.token != "" {
+				req.Header.Set(fiber.HeaderAuthorization, "Bearer "+tt.token)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("app.Test: %v", err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if string(body) != tt.want {
+				t.Errorf("key = %q, want %q", body, tt.want)
+			}
+		})
+	}
+}
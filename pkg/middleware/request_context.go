@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"pre-test-gallery-service/pkg/apperror"
+	"pre-test-gallery-service/pkg/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ErrShuttingDown answers requests whose context was cancelled because the
+// server is shutting down
+var ErrShuttingDown = apperror.New(apperror.KindUnavailable, apperror.CodeServiceUnavailable, "Server is shutting down")
+
+// RequestContext gives every request a cancellable context with a deadline
+// that services and repositories receive through c.UserContext(). The
+// context is also cancelled when the server shuts down. fasthttp does not
+// report client disconnects, so abandoned requests end at the deadline.
+//
+// When the handler fails after the context ended, the response is replaced
+// with 504 on timeout or 503 on shutdown, which the client is still
+// connected to receive.
+func RequestContext(timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+		defer cancel()
+
+		stop := context.AfterFunc(c.Context(), cancel)
+		defer stop()
+
+		c.SetUserContext(ctx)
+		err := c.Next()
+
+		if ctx.Err() == nil || (err == nil && c.Response().StatusCode() < fiber.StatusInternalServerError) {
+			return err
+		}
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return utils.SendError(c, apperror.ErrRequestTimeout.Wrap(ctx.Err()))
+		}
+		return utils.SendError(c, ErrShuttingDown.Wrap(ctx.Err()))
+	}
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRequestContext(t *testing.T) {
+	app := fiber.New()
+	app.Use(RequestContext(20 * time.Millisecond))
+	app.Get("/fast", func(c *fiber.Ctx) error {
+		if _, ok := c.UserContext().Deadline(); !ok {
+			return c.SendStatus(fiber.StatusTeapot)
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+	app.Get("/slow", func(c *fiber.Ctx) error {
+		<-c.UserContext().Done()
+		return c.Status(fiber.StatusInternalServerError).SendString(c.UserContext().Err().Error())
+	})
+	app.Get("/returns-error", func(c *fiber.Ctx) error {
+		<-c.UserContext().Done()
+		return c.UserContext().Err()
+	})
+	app.Get("/cancelled", func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithCancel(c.UserContext())
+		cancel()
+		return ctx.Err()
+	})
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{path: "/fast", want: fiber.StatusOK},
+		{path: "/slow", want: fiber.StatusGatewayTimeout},
+		{path: "/returns-error", want: fiber.StatusGatewayTimeout},
+		// Cancelling a derived context does not cancel the request itself
+		{path: "/cancelled", want: fiber.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, tt.path, nil))
+		if err != nil {
+			t.Fatalf("app.Test(%s): %v", tt.path, err)
+		}
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.path, resp.StatusCode, tt.want)
+		}
+	}
+}
+
+func TestRequestContextShutdown(t *testing.T) {
+	started := make(chan struct{})
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Use(RequestContext(time.Minute))
+	app.Get("/", func(c *fiber.Ctx) error {
+		close(started)
+		<-c.UserContext().Done()
+		return c.UserContext().Err()
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	go func() { _ = app.Listener(ln) }()
+
+	type result struct {
+		status int
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/")
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		resp.Body.Close()
+		done <- result{status: resp.StatusCode}
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request did not reach the handler")
+	}
+	if err := app.ShutdownWithTimeout(5 * time.Second); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	res := <-done
+	if res.err != nil {
+		t.Fatalf("GET: %v", res.err)
+	}
+	if res.status != fiber.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", res.status, fiber.StatusServiceUnavailable)
+	}
+}
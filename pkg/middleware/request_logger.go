@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"log/slog"
+	"pre-test-gallery-service/pkg/logger"
+	"regexp"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const HeaderRequestID = "X-Request-ID"
+
+// Incoming request IDs are only propagated when they look sane
+var requestIDPattern = regexp.MustCompile(`^[a-zA-Z0-9._:-]{1,128}$`)
+
+// RequestID assigns or propagates the X-Request-ID of the request and puts a
+// logger carrying it into the user context for services and repositories
+func RequestID(base *slog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Get(HeaderRequestID)
+		if !requestIDPattern.MatchString(id) {
+			id = utils.UUIDv4()
+		}
+
+		l := base.With("request_id", id)
+		if span := trace.SpanContextFromContext(c.UserContext()); span.IsValid() {
+			l = l.With("trace_id", span.TraceID().String())
+		}
+
+		c.Set(HeaderRequestID, id)
+		c.Locals("request_id", id)
+		c.SetUserContext(logger.WithContext(c.UserContext(), l))
+
+		return c.Next()
+	}
+}
+
+// RequestLogger writes one structured access log line per request
+func RequestLogger() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		nextAndRenderError(c)
+
+		status := c.Response().StatusCode()
+		attrs := []slog.Attr{
+			slog.String("method", c.Method()),
+			slog.String("route", c.Route().Path),
+			slog.String("path", c.Path()),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes_in", bodySize(c)),
+			slog.Int("bytes_out", len(c.Response().Body())),
+			slog.String("ip", GetClientIP(c)),
+		}
+		if user, ok := c.Locals("user").(string); ok {
+			attrs = append(attrs, slog.String("user", user))
+		}
+		if tenant, ok := c.Locals("tenant").(string); ok {
+			attrs = append(attrs, slog.String("tenant", tenant))
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= fiber.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= fiber.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		logger.FromContext(c.UserContext()).LogAttrs(c.UserContext(), level, "request", attrs...)
+		return nil
+	}
+}
+
+// nextAndRenderError runs the rest of the chain and lets the app's error
+// handler write the response for its error, so middleware reading the
+// response afterwards sees the final status. The innermost caller renders
+// the error; the ones around it then see nil
+func nextAndRenderError(c *fiber.Ctx) {
+	if err := c.Next(); err != nil {
+		if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+			_ = c.SendStatus(fiber.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"pre-test-gallery-service/pkg/logger"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRequestIDAndLogger(t *testing.T) {
+	var buf bytes.Buffer
+	base := logger.New(&buf, "info")
+
+	app := fiber.New()
+	app.Use(RequestID(base), RequestLogger())
+	app.Get("/tags/:tag", func(c *fiber.Ctx) error {
+		c.Locals("user", "user-1")
+		logger.FromContext(c.UserContext()).Info("from handler")
+		return c.Status(fiber.StatusCreated).SendString("ok")
+	})
+
+	req := httptest.NewRequest(fiber.MethodGet, "/tags/cats", nil)
+	req.Header.Set(HeaderRequestID, "abc-123")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	if got := resp.Header.Get(HeaderRequestID); got != "abc-123" {
+		t.Fatalf("X-Request-ID = %q, want propagated value", got)
+	}
+
+	var lines []map[string]interface{}
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatalf("log line is not JSON: %s", line)
+		}
+		lines = append(lines, entry)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("got %d log lines, want 2", len(lines))
+	}
+
+	for _, entry := range lines {
+		if entry["request_id"] != "abc-123" {
+			t.Errorf("request_id = %v in %v", entry["request_id"], entry)
+		}
+	}
+
+	access := lines[1]
+	if access["route"] != "/tags/:tag" || access["status"] != float64(fiber.StatusCreated) || access["user"] != "user-1" || access["bytes_out"] != float64(2) {
+		t.Errorf("unexpected access log %v", access)
+	}
+}
+
+func TestRequestIDGeneratedForInvalidHeader(t *testing.T) {
+	var buf bytes.Buffer
+
+	app := fiber.New()
+	app.Use(RequestID(logger.New(&buf, "info")))
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	req := httptest.NewRequest(fiber.MethodGet, "/", nil)
+	req.Header.Set(HeaderRequestID, "bad id\twith spaces")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+
+	got := resp.Header.Get(HeaderRequestID)
+	if got == "" || got == "bad id\twith spaces" {
+		t.Fatalf("X-Request-ID = %q, want a generated ID", got)
+	}
+}
+
+func TestObservingMiddlewareRenderErrorOnce(t *testing.T) {
+	var rendered int
+	app := fiber.New(fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			rendered++
+			return c.Status(fiber.StatusConflict).SendString(err.Error())
+		},
+	})
+	app.Use(RequestID(logger.New(&bytes.Buffer{}, "info")), RequestLogger(), Metrics(), Tracing())
+	app.Get("/", func(c *fiber.Ctx) error { return fiber.ErrConflict })
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusConflict {
+		t.Errorf("status = %d, want %d", resp.StatusCode, fiber.StatusConflict)
+	}
+	if rendered != 1 {
+		t.Errorf("error handler ran %d times, want 1", rendered)
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"pre-test-gallery-service/pkg/apperror"
+	"pre-test-gallery-service/pkg/tenant"
+	"pre-test-gallery-service/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	ErrTenantMismatch   = apperror.New(apperror.KindForbidden, "TENANT_MISMATCH", "Tenant does not match token")
+	ErrTenantNotAllowed = apperror.New(apperror.KindForbidden, "TENANT_NOT_ALLOWED", "Tenant must come from the access token")
+	ErrTenantRequired   = apperror.New(apperror.KindInvalid, "TENANT_REQUIRED", "Tenant is required")
+	ErrInvalidTenant    = apperror.New(apperror.KindInvalid, "INVALID_TENANT", "Invalid tenant")
+)
+
+// ResolveTenant picks the workspace for the request: the tenant claim of the
+// access token wins, then the tenant header, then the configured default.
+// With fromIdentity, as when auth is enabled, the header is only a check:
+// requests without a token tenant may only use the default tenant.
+// The tenant is stored in locals and in the user context for repositories.
+func ResolveTenant(header, defaultTenant string, fromIdentity bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		tokenTenant, _ := c.Locals("tenant").(string)
+		headerTenant := c.Get(header)
+
+		id := tokenTenant
+		switch {
+		case id != "":
+			if headerTenant != "" && headerTenant != tokenTenant {
+				return utils.SendError(c, ErrTenantMismatch)
+			}
+		case fromIdentity:
+			if headerTenant != "" && headerTenant != defaultTenant {
+				return utils.SendError(c, ErrTenantNotAllowed)
+			}
+		default:
+			id = headerTenant
+		}
+		if id == "" {
+			id = defaultTenant
+		}
+
+		if id == "" {
+			return utils.SendError(c, ErrTenantRequired)
+		}
+		if err := tenant.Validate(id); err != nil {
+			return utils.SendError(c, ErrInvalidTenant.Wrap(err))
+		}
+
+		c.Locals("tenant", id)
+		c.SetUserContext(tenant.WithTenant(c.UserContext(), id))
+
+		return c.Next()
+	}
+}
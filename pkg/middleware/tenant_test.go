@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"pre-test-gallery-service/pkg/tenant"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestResolveTenant(t *testing.T) {
+	tests := []struct {
+		name         string
+		fromIdentity bool
+		user         string
+		tokenTenant  string
+		header       string
+		want         string
+		wantStatus   int
+	}{
+		{name: "header without auth", header: "acme", want: "acme", wantStatus: fiber.StatusOK},
+		{name: "default without auth", want: "default", wantStatus: fiber.StatusOK},
+		{name: "invalid header", header: "a b", wantStatus: fiber.StatusBadRequest},
+		{name: "token tenant", fromIdentity: true, user: "alice", tokenTenant: "acme", want: "acme", wantStatus: fiber.StatusOK},
+		{name: "matching header", fromIdentity: true, user: "alice", tokenTenant: "acme", header: "acme", want: "acme", wantStatus: fiber.StatusOK},
+		{name: "mismatched header", fromIdentity: true, user: "alice", tokenTenant: "acme", header: "other", wantStatus: fiber.StatusForbidden},
+		{name: "anonymous with header", fromIdentity: true, header: "other", wantStatus: fiber.StatusForbidden},
+		{name: "claimless token with header", fromIdentity: true, user: "alice", header: "other", wantStatus: fiber.StatusForbidden},
+		{name: "anonymous default", fromIdentity: true, want: "default", wantStatus: fiber.StatusOK},
+		{name: "anonymous naming default", fromIdentity: true, header: "default", want: "default", wantStatus: fiber.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Use(func(c *fiber.Ctx) error {
+				// Stands in for Authenticate
+				if tt.user != "" {
+					c.Locals("user", tt.user)
+				}
+				if tt.tokenTenant != "" {
+					c.Locals("tenant", tt.tokenTenant)
+				}
+				return c.Next()
+			})
+			app.Use(ResolveTenant("X-Tenant-ID", "default", tt.fromIdentity))
+			app.Get("/", func(c *fiber.Ctx) error {
+				id, err := tenant.FromContext(c.UserContext())
+				if err != nil {
+					return err
+				}
+				return c.SendString(id)
+			})
+
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Tenant-ID", tt.header)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Test: %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+
+			if tt.want != "" {
+				body, err := io.ReadAll(resp.Body)
+				if err != nil {
+					t.Fatalf("read body: %v", err)
+				}
+				if got := string(body); got != tt.want {
+					t.Errorf("tenant = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveTenantRequiresOne(t *testing.T) {
+	app := fiber.New()
+	app.Use(ResolveTenant("X-Tenant-ID", "", true))
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	if err != nil {
+		t.Fatalf("Test: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusBadRequest)
+	}
+}
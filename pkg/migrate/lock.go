@@ -0,0 +1,149 @@
+package migrate
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	"pre-test-gallery-service/pkg/logger"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	lockID = "migrate"
+	// leaseTTL bounds how long a crashed replica blocks the others
+	leaseTTL     = 30 * time.Second
+	pollInterval = time.Second
+)
+
+// Lock is a lease stored in a single document. It expires unless renewed,
+// so a replica that dies while migrating does not block the others forever
+type Lock struct {
+	collection *mongo.Collection
+	owner      string
+	timeout    time.Duration
+	ttl        time.Duration
+	poll       time.Duration
+}
+
+// NewLock waits up to timeout for the lease in Acquire
+func NewLock(collection *mongo.Collection, timeout time.Duration) *Lock {
+	return &Lock{
+		collection: collection,
+		owner:      newOwner(),
+		timeout:    timeout,
+		ttl:        leaseTTL,
+		poll:       pollInterval,
+	}
+}
+
+// Acquire blocks until the lease is held and renews it in the background.
+// The returned context is cancelled when the lease is lost or released
+func (l *Lock) Acquire(ctx context.Context) (context.Context, func(), error) {
+	waitCtx, cancelWait := context.WithTimeout(ctx, l.timeout)
+	defer cancelWait()
+
+	for {
+		acquired, err := l.tryAcquire(waitCtx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("acquire migration lock: %w", err)
+		}
+		if acquired {
+			break
+		}
+
+		logger.FromContext(ctx).Info("Waiting for the migration lock held by another replica")
+		select {
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+			return nil, nil, ErrLockTimeout
+		case <-time.After(l.poll):
+		}
+	}
+
+	lockCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go l.renew(lockCtx, cancel, done)
+
+	release := func() {
+		cancel()
+		<-done
+
+		releaseCtx, cancelRelease := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancelRelease()
+		if _, err := l.collection.DeleteOne(releaseCtx, bson.M{"_id": lockID, "owner": l.owner}); err != nil {
+			logger.FromContext(ctx).Error("Failed to release the migration lock", "error", err)
+		}
+	}
+	return lockCtx, release, nil
+}
+
+// tryAcquire takes the lease when it is free, expired or already ours. When
+// another replica holds it the upsert collides on _id
+func (l *Lock) tryAcquire(ctx context.Context) (bool, error) {
+	now := time.Now().UTC()
+	filter := bson.M{
+		"_id": lockID,
+		"$or": bson.A{
+			bson.M{"expires_at": bson.M{"$lte": now}},
+			bson.M{"owner": l.owner},
+		},
+	}
+	update := bson.M{"$set": bson.M{
+		"owner":       l.owner,
+		"acquired_at": now,
+		"expires_at":  now.Add(l.ttl),
+	}}
+
+	_, err := l.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (l *Lock) renew(ctx context.Context, lost context.CancelFunc, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result, err := l.collection.UpdateOne(ctx,
+				bson.M{"_id": lockID, "owner": l.owner},
+				bson.M{"$set": bson.M{"expires_at": time.Now().UTC().Add(l.ttl)}},
+			)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil || result.MatchedCount == 0 {
+				logger.FromContext(ctx).Error("Lost the migration lock", "error", err)
+				lost()
+				return
+			}
+		}
+	}
+}
+
+// newOwner identifies this process in the lock document
+func newOwner() string {
+	host, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(suffix))
+}
@@ -0,0 +1,128 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"pre-test-gallery-service/pkg/mongotest"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// newTestLock shortens the lease so expiry and renewal happen within a test
+func newTestLock(db *mongo.Database, timeout time.Duration) *Lock {
+	l := NewLock(db.Collection(LockCollection), timeout)
+	l.ttl = 300 * time.Millisecond
+	l.poll = 20 * time.Millisecond
+	return l
+}
+
+func TestLock(t *testing.T) {
+	client := mongotest.Client(t)
+
+	t.Run("Excludes", func(t *testing.T) {
+		db := mongotest.Database(t, client)
+		ctx := context.Background()
+
+		_, release, err := newTestLock(db, time.Second).Acquire(ctx)
+		if err != nil {
+			t.Fatalf("Acquire: %v", err)
+		}
+
+		other := newTestLock(db, 100*time.Millisecond)
+		if _, _, err := other.Acquire(ctx); !errors.Is(err, ErrLockTimeout) {
+			t.Fatalf("second Acquire = %v, want ErrLockTimeout", err)
+		}
+
+		release()
+		_, releaseOther, err := other.Acquire(ctx)
+		if err != nil {
+			t.Fatalf("Acquire after release: %v", err)
+		}
+		releaseOther()
+	})
+
+	t.Run("RenewsLease", func(t *testing.T) {
+		db := mongotest.Database(t, client)
+		ctx := context.Background()
+
+		lockCtx, release, err := newTestLock(db, time.Second).Acquire(ctx)
+		if err != nil {
+			t.Fatalf("Acquire: %v", err)
+		}
+		defer release()
+
+		// Well past the 300ms lease, which the holder keeps renewing
+		if _, _, err := newTestLock(db, time.Second).Acquire(ctx); !errors.Is(err, ErrLockTimeout) {
+			t.Fatalf("Acquire of a renewed lease = %v, want ErrLockTimeout", err)
+		}
+		if lockCtx.Err() != nil {
+			t.Fatalf("holder lost its lease: %v", lockCtx.Err())
+		}
+	})
+
+	t.Run("TakesOverExpiredLease", func(t *testing.T) {
+		db := mongotest.Database(t, client)
+		ctx := context.Background()
+
+		// A replica that crashed while holding the lease
+		_, err := db.Collection(LockCollection).InsertOne(ctx, bson.M{
+			"_id":        lockID,
+			"owner":      "crashed",
+			"expires_at": time.Now().UTC().Add(-time.Second),
+		})
+		if err != nil {
+			t.Fatalf("insert expired lease: %v", err)
+		}
+
+		_, release, err := newTestLock(db, 100*time.Millisecond).Acquire(ctx)
+		if err != nil {
+			t.Fatalf("Acquire of an expired lease: %v", err)
+		}
+		release()
+	})
+
+	t.Run("CancelsContextWhenLost", func(t *testing.T) {
+		db := mongotest.Database(t, client)
+		ctx := context.Background()
+
+		lockCtx, release, err := newTestLock(db, time.Second).Acquire(ctx)
+		if err != nil {
+			t.Fatalf("Acquire: %v", err)
+		}
+		defer release()
+
+		// Another replica took the lease over, e.g. after a long pause
+		if _, err := db.Collection(LockCollection).UpdateOne(ctx, bson.M{"_id": lockID}, bson.M{"$set": bson.M{"owner": "other"}}); err != nil {
+			t.Fatalf("steal lease: %v", err)
+		}
+
+		select {
+		case <-lockCtx.Done():
+		case <-time.After(time.Second):
+			t.Fatal("lock context is still alive after the lease was lost")
+		}
+	})
+
+	t.Run("ReleaseKeepsOthersLease", func(t *testing.T) {
+		db := mongotest.Database(t, client)
+		ctx := context.Background()
+
+		_, release, err := newTestLock(db, time.Second).Acquire(ctx)
+		if err != nil {
+			t.Fatalf("Acquire: %v", err)
+		}
+		if _, err := db.Collection(LockCollection).UpdateOne(ctx, bson.M{"_id": lockID}, bson.M{"$set": bson.M{"owner": "other"}}); err != nil {
+			t.Fatalf("steal lease: %v", err)
+		}
+		release()
+
+		count, err := db.Collection(LockCollection).CountDocuments(ctx, bson.M{"owner": "other"})
+		if err != nil || count != 1 {
+			t.Fatalf("lease of the new owner: count %d, %v, want it kept", count, err)
+		}
+	})
+}
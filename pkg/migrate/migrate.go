@@ -0,0 +1,246 @@
+// Package migrate applies versioned schema migrations to a MongoDB database.
+// Applied versions are recorded in the schema_migrations collection and a
+// lease in schema_migrations_lock makes sure only one replica migrates at a
+// time. MongoDB cannot run index builds inside a transaction, so a migration
+// that fails half way is not recorded and must be safe to run again.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"pre-test-gallery-service/pkg/logger"
+	"pre-test-gallery-service/pkg/tracing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// Collection records one document per applied version
+	Collection = "schema_migrations"
+	// LockCollection holds the lease of the replica that is migrating
+	LockCollection = "schema_migrations_lock"
+)
+
+var (
+	ErrIrreversible = errors.New("migration cannot be reverted")
+	ErrLockTimeout  = errors.New("timed out waiting for the migration lock")
+)
+
+// Func changes the schema or data of db; it must be idempotent
+type Func func(ctx context.Context, db *mongo.Database) error
+
+type Migration struct {
+	Version     int
+	Description string
+	Up          Func
+	// Down reverts Up; nil marks the migration irreversible
+	Down Func
+}
+
+// Record is the schema_migrations document of an applied version
+type Record struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+	DurationMs  int64     `bson:"duration_ms"`
+}
+
+// Status describes a known migration; AppliedAt is zero while it is pending
+type Status struct {
+	Version     int
+	Description string
+	AppliedAt   time.Time
+}
+
+func (s Status) Applied() bool {
+	return !s.AppliedAt.IsZero()
+}
+
+type Runner struct {
+	db         *mongo.Database
+	migrations []Migration
+	lock       *Lock
+}
+
+// New checks that versions are positive and unique and that every
+// migration has an Up func; migrations run in version order
+func New(db *mongo.Database, migrations []Migration, lockTimeout time.Duration) (*Runner, error) {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	for i, m := range sorted {
+		if m.Version <= 0 {
+			return nil, fmt.Errorf("migration %q: version must be positive", m.Description)
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("migration version %d is used twice", m.Version)
+		}
+		if m.Up == nil {
+			return nil, fmt.Errorf("migration %d: Up is required", m.Version)
+		}
+	}
+
+	return &Runner{
+		db:         db,
+		migrations: sorted,
+		lock:       NewLock(db.Collection(LockCollection), lockTimeout),
+	}, nil
+}
+
+// Up applies every pending migration and returns how many ran
+func (r *Runner) Up(ctx context.Context) (applied int, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "migrate.Up")
+	defer func() { tracing.End(span, err) }()
+
+	err = r.withLock(ctx, func(ctx context.Context) error {
+		records, err := r.records(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range r.migrations {
+			if _, ok := records[m.Version]; ok {
+				continue
+			}
+			if err := r.apply(ctx, m); err != nil {
+				return err
+			}
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations, newest first
+func (r *Runner) Down(ctx context.Context, steps int) (reverted int, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "migrate.Down")
+	defer func() { tracing.End(span, err) }()
+
+	err = r.withLock(ctx, func(ctx context.Context) error {
+		records, err := r.records(ctx)
+		if err != nil {
+			return err
+		}
+
+		for i := len(r.migrations) - 1; i >= 0 && reverted < steps; i-- {
+			m := r.migrations[i]
+			if _, ok := records[m.Version]; !ok {
+				continue
+			}
+			if err := r.revert(ctx, m); err != nil {
+				return err
+			}
+			reverted++
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every known migration in version order
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	records, err := r.records(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(r.migrations))
+	for _, m := range r.migrations {
+		statuses = append(statuses, Status{
+			Version:     m.Version,
+			Description: m.Description,
+			AppliedAt:   records[m.Version].AppliedAt,
+		})
+	}
+	return statuses, nil
+}
+
+func (r *Runner) apply(ctx context.Context, m Migration) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "migrate.apply")
+	span.SetAttributes(attribute.Int("migration.version", m.Version))
+	defer func() { tracing.End(span, err) }()
+
+	log := logger.FromContext(ctx).With("version", m.Version, "description", m.Description)
+	log.Info("Applying migration")
+
+	start := time.Now()
+	if err := m.Up(ctx, r.db); err != nil {
+		return fmt.Errorf("migration %d (%s): %w", m.Version, m.Description, err)
+	}
+	elapsed := time.Since(start)
+
+	_, err = r.db.Collection(Collection).InsertOne(ctx, Record{
+		Version:     m.Version,
+		Description: m.Description,
+		AppliedAt:   time.Now().UTC(),
+		DurationMs:  elapsed.Milliseconds(),
+	})
+	if err != nil {
+		return fmt.Errorf("record migration %d: %w", m.Version, err)
+	}
+
+	log.Info("Applied migration", "duration_ms", elapsed.Milliseconds())
+	return nil
+}
+
+func (r *Runner) revert(ctx context.Context, m Migration) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "migrate.revert")
+	span.SetAttributes(attribute.Int("migration.version", m.Version))
+	defer func() { tracing.End(span, err) }()
+
+	if m.Down == nil {
+		return fmt.Errorf("migration %d (%s): %w", m.Version, m.Description, ErrIrreversible)
+	}
+
+	log := logger.FromContext(ctx).With("version", m.Version, "description", m.Description)
+	log.Info("Reverting migration")
+
+	if err := m.Down(ctx, r.db); err != nil {
+		return fmt.Errorf("revert migration %d (%s): %w", m.Version, m.Description, err)
+	}
+	if _, err := r.db.Collection(Collection).DeleteOne(ctx, bson.M{"_id": m.Version}); err != nil {
+		return fmt.Errorf("unrecord migration %d: %w", m.Version, err)
+	}
+
+	log.Info("Reverted migration")
+	return nil
+}
+
+func (r *Runner) records(ctx context.Context) (map[int]Record, error) {
+	cursor, err := r.db.Collection(Collection).Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", Collection, err)
+	}
+
+	var list []Record
+	if err := cursor.All(ctx, &list); err != nil {
+		return nil, fmt.Errorf("read %s: %w", Collection, err)
+	}
+
+	records := make(map[int]Record, len(list))
+	for _, record := range list {
+		records[record.Version] = record
+	}
+	return records, nil
+}
+
+// withLock runs fn while holding the migration lease; fn's context is
+// cancelled if the lease is lost
+func (r *Runner) withLock(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, release, err := r.lock.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return fn(ctx)
+}
@@ -0,0 +1,80 @@
+package migrate
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testDatabase returns a handle without a server; the driver only dials
+// when a command runs
+func testDatabase(t *testing.T) *mongo.Database {
+	t.Helper()
+
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI("mongodb://127.0.0.1:1"))
+	if err != nil {
+		t.Fatalf("mongo.Connect: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(context.Background()) })
+	return client.Database("migrate_test")
+}
+
+func noop(context.Context, *mongo.Database) error { return nil }
+
+func TestNewOrdersMigrations(t *testing.T) {
+	runner, err := New(testDatabase(t), []Migration{
+		{Version: 3, Description: "third", Up: noop},
+		{Version: 1, Description: "first", Up: noop},
+		{Version: 2, Description: "second", Up: noop, Down: noop},
+	}, time.Second)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	for i, m := range runner.migrations {
+		if m.Version != i+1 {
+			t.Fatalf("migrations[%d].Version = %d, want %d", i, m.Version, i+1)
+		}
+	}
+}
+
+func TestNewRejectsInvalidMigrations(t *testing.T) {
+	tests := []struct {
+		name       string
+		migrations []Migration
+		wantErr    string
+	}{
+		{
+			name:       "zero version",
+			migrations: []Migration{{Version: 0, Description: "zero", Up: noop}},
+			wantErr:    "version must be positive",
+		},
+		{
+			name: "duplicate version",
+			migrations: []Migration{
+				{Version: 1, Description: "a", Up: noop},
+				{Version: 1, Description: "b", Up: noop},
+			},
+			wantErr: "version 1 is used twice",
+		},
+		{
+			name:       "missing up",
+			migrations: []Migration{{Version: 1, Description: "down only", Down: noop}},
+			wantErr:    "Up is required",
+		},
+	}
+
+	db := testDatabase(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(db, tt.migrations, time.Second)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("New error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"pre-test-gallery-service/pkg/mongotest"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// countingMigrations creates one collection per version and counts how
+// often each Up and Down runs
+func countingMigrations(ups, downs []atomic.Int32) []Migration {
+	migrations := make([]Migration, len(ups))
+	for i := range migrations {
+		i := i
+		name := string(rune('a' + i))
+		migrations[i] = Migration{
+			Version:     i + 1,
+			Description: "create " + name,
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				ups[i].Add(1)
+				return db.CreateCollection(ctx, name)
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				downs[i].Add(1)
+				return db.Collection(name).Drop(ctx)
+			},
+		}
+	}
+	return migrations
+}
+
+func newTestRunner(t *testing.T, db *mongo.Database, migrations []Migration) *Runner {
+	t.Helper()
+
+	runner, err := New(db, migrations, 10*time.Second)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	runner.lock.ttl = 300 * time.Millisecond
+	runner.lock.poll = 20 * time.Millisecond
+	return runner
+}
+
+func TestRunner(t *testing.T) {
+	client := mongotest.Client(t)
+
+	t.Run("UpAndDown", func(t *testing.T) {
+		db := mongotest.Database(t, client)
+		ctx := context.Background()
+		ups, downs := make([]atomic.Int32, 3), make([]atomic.Int32, 3)
+		runner := newTestRunner(t, db, countingMigrations(ups, downs))
+
+		if applied, err := runner.Up(ctx); err != nil || applied != 3 {
+			t.Fatalf("Up = %d, %v, want 3", applied, err)
+		}
+		if applied, err := runner.Up(ctx); err != nil || applied != 0 {
+			t.Fatalf("second Up = %d, %v, want 0", applied, err)
+		}
+
+		statuses, err := runner.Status(ctx)
+		if err != nil {
+			t.Fatalf("Status: %v", err)
+		}
+		for _, s := range statuses {
+			if !s.Applied() {
+				t.Errorf("version %d is pending after Up", s.Version)
+			}
+		}
+
+		if reverted, err := runner.Down(ctx, 1); err != nil || reverted != 1 {
+			t.Fatalf("Down = %d, %v, want 1", reverted, err)
+		}
+		if downs[2].Load() != 1 || downs[1].Load() != 0 {
+			t.Fatalf("Down reverted %d/%d of versions 2/3, want only 3", downs[1].Load(), downs[2].Load())
+		}
+		names, err := db.ListCollectionNames(ctx, bson.M{"name": "c"})
+		if err != nil || len(names) != 0 {
+			t.Fatalf("collection of the reverted version: %v, %v", names, err)
+		}
+
+		if applied, err := runner.Up(ctx); err != nil || applied != 1 {
+			t.Fatalf("Up after Down = %d, %v, want 1", applied, err)
+		}
+		for i, want := range []int32{1, 1, 2} {
+			if ups[i].Load() != want {
+				t.Errorf("version %d ran Up %d times, want %d", i+1, ups[i].Load(), want)
+			}
+		}
+	})
+
+	t.Run("Irreversible", func(t *testing.T) {
+		db := mongotest.Database(t, client)
+		ctx := context.Background()
+		runner := newTestRunner(t, db, []Migration{{Version: 1, Description: "one way", Up: noop}})
+
+		if _, err := runner.Up(ctx); err != nil {
+			t.Fatalf("Up: %v", err)
+		}
+		if _, err := runner.Down(ctx, 1); !errors.Is(err, ErrIrreversible) {
+			t.Fatalf("Down = %v, want ErrIrreversible", err)
+		}
+		statuses, err := runner.Status(ctx)
+		if err != nil || !statuses[0].Applied() {
+			t.Fatalf("irreversible migration is no longer recorded: %v, %v", statuses, err)
+		}
+	})
+
+	t.Run("FailedUpIsNotRecorded", func(t *testing.T) {
+		db := mongotest.Database(t, client)
+		ctx := context.Background()
+		failing := errors.New("boom")
+		runner := newTestRunner(t, db, []Migration{
+			{Version: 1, Description: "works", Up: noop},
+			{Version: 2, Description: "fails", Up: func(context.Context, *mongo.Database) error { return failing }},
+		})
+
+		if applied, err := runner.Up(ctx); !errors.Is(err, failing) || applied != 1 {
+			t.Fatalf("Up = %d, %v, want 1 and the migration error", applied, err)
+		}
+		statuses, err := runner.Status(ctx)
+		if err != nil {
+			t.Fatalf("Status: %v", err)
+		}
+		if !statuses[0].Applied() || statuses[1].Applied() {
+			t.Fatalf("statuses = %+v, want only version 1 applied", statuses)
+		}
+	})
+
+	t.Run("CompetingRunners", func(t *testing.T) {
+		db := mongotest.Database(t, client)
+		ups, downs := make([]atomic.Int32, 3), make([]atomic.Int32, 3)
+		migrations := countingMigrations(ups, downs)
+
+		// Slow enough that the runners overlap and the lease is renewed
+		first := migrations[0].Up
+		migrations[0].Up = func(ctx context.Context, db *mongo.Database) error {
+			time.Sleep(500 * time.Millisecond)
+			return first(ctx, db)
+		}
+
+		const replicas = 4
+		applied := make([]int, replicas)
+		errs := make([]error, replicas)
+		var wg sync.WaitGroup
+		for i := range replicas {
+			runner := newTestRunner(t, db, migrations)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				applied[i], errs[i] = runner.Up(context.Background())
+			}()
+		}
+		wg.Wait()
+
+		total := 0
+		for i := range replicas {
+			if errs[i] != nil {
+				t.Errorf("runner %d: %v", i, errs[i])
+			}
+			total += applied[i]
+		}
+		if total != 3 {
+			t.Errorf("runners applied %d migrations in total, want 3", total)
+		}
+		for i := range ups {
+			if ups[i].Load() != 1 {
+				t.Errorf("version %d ran %d times, want once", i+1, ups[i].Load())
+			}
+		}
+	})
+}
@@ -0,0 +1,99 @@
+// Package mongotest connects tests to MongoDB: the server at MONGO_TEST_URI,
+// or a throwaway mongod started from the PATH. Tests are skipped when
+// neither is available and in -short mode
+package mongotest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var databases atomic.Int64
+
+// Client connects to the test server; it is disconnected, and a started
+// mongod stopped, when t ends
+func Client(t testing.TB) *mongo.Client {
+	t.Helper()
+
+	if testing.Short() {
+		t.Skip("needs MongoDB")
+	}
+
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		uri = startMongod(t)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect %s: %v", uri, err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(context.Background()) })
+
+	for {
+		if err = client.Ping(ctx, nil); err == nil {
+			return client
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("ping %s: %v", uri, err)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+// Database returns an empty database of its own, dropped when t ends
+func Database(t testing.TB, client *mongo.Client) *mongo.Database {
+	t.Helper()
+
+	db := client.Database(fmt.Sprintf("gallery_test_%d_%d", os.Getpid(), databases.Add(1)))
+	t.Cleanup(func() { _ = db.Drop(context.Background()) })
+	return db
+}
+
+// startMongod runs a mongod on a free port with its data in a temporary
+// directory and returns its URI
+func startMongod(t testing.TB) string {
+	t.Helper()
+
+	bin, err := exec.LookPath("mongod")
+	if err != nil {
+		t.Skip("set MONGO_TEST_URI or put mongod on the PATH to run the MongoDB tests")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("find a free port: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+
+	var output strings.Builder
+	cmd := exec.Command(bin, "--dbpath", t.TempDir(), "--bind_ip", "127.0.0.1", "--port", fmt.Sprint(port), "--quiet")
+	cmd.Stdout, cmd.Stderr = &output, &output
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start mongod: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		if t.Failed() {
+			t.Logf("mongod output:\n%s", output.String())
+		}
+	})
+
+	return fmt.Sprintf("mongodb://127.0.0.1:%d/?directConnection=true", port)
+}
@@ -0,0 +1,35 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"regexp"
+)
+
+var (
+	ErrMissingTenant = errors.New("tenant is required")
+	ErrInvalidTenant = errors.New("invalid tenant id")
+)
+
+var tenantIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+type contextKey struct{}
+
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+func FromContext(ctx context.Context) (string, error) {
+	id, ok := ctx.Value(contextKey{}).(string)
+	if !ok || id == "" {
+		return "", ErrMissingTenant
+	}
+	return id, nil
+}
+
+func Validate(id string) error {
+	if !tenantIDPattern.MatchString(id) {
+		return ErrInvalidTenant
+	}
+	return nil
+}
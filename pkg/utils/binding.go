@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"errors"
+	"pre-test-gallery-service/pkg/apperror"
+	"reflect"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	ErrInvalidQuery      = apperror.New(apperror.KindInvalid, "INVALID_QUERY", "Invalid query parameters")
+	ErrInvalidPathParams = apperror.New(apperror.KindInvalid, "INVALID_PATH_PARAMS", "Invalid path parameters")
+)
+
+// Bind fills out from the request body (json tags), the query string (query
+// tags) and the route parameters (params tags), and then validates it. Each
+// source only fills the fields tagged for it, as fiber's parsers would
+// otherwise match untagged fields by name and let ?name= overwrite the body.
+// The returned errors are ready to be returned from a handler.
+func Bind(c *fiber.Ctx, out interface{}) error {
+	if len(c.Body()) > 0 {
+		if err := parseTagged(out, "json", c.BodyParser); err != nil {
+			return apperror.ErrInvalidRequestBody.Wrap(err)
+		}
+	}
+
+	if len(c.Request().URI().QueryString()) > 0 {
+		if err := parseTagged(out, "query", c.QueryParser); err != nil {
+			return ErrInvalidQuery.Wrap(err)
+		}
+	}
+
+	if len(c.Route().Params) > 0 {
+		if err := parseTagged(out, "params", c.ParamsParser); err != nil {
+			return ErrInvalidPathParams.Wrap(err)
+		}
+	}
+
+	return validate(out)
+}
+
+// BindQuery is Bind for handlers whose body is not JSON, such as file
+// uploads: only the query string is parsed before validation
+func BindQuery(c *fiber.Ctx, out interface{}) error {
+	if err := parseTagged(out, "query", c.QueryParser); err != nil {
+		return ErrInvalidQuery.Wrap(err)
+	}
+	return validate(out)
+}
+
+// parseTagged runs parse on a struct made of the fields of out tagged with
+// key, then copies them back; out is left alone when no field has the tag
+func parseTagged(out interface{}, key string, parse func(interface{}) error) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return parse(out)
+	}
+	v = v.Elem()
+
+	var fields []reflect.StructField
+	var indexes []int
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.IsExported() && field.Tag.Get(key) != "" {
+			fields = append(fields, reflect.StructField{Name: field.Name, Type: field.Type, Tag: field.Tag})
+			indexes = append(indexes, i)
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	tagged := reflect.New(reflect.StructOf(fields)).Elem()
+	for i, index := range indexes {
+		tagged.Field(i).Set(v.Field(index))
+	}
+	if err := parse(tagged.Addr().Interface()); err != nil {
+		return err
+	}
+	for i, index := range indexes {
+		v.Field(index).Set(tagged.Field(i))
+	}
+	return nil
+}
+
+func validate(out interface{}) error {
+	if err := ValidateStruct(out); err != nil {
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			return ValidationError(err)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"pre-test-gallery-service/pkg/apperror"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type bindRequest struct {
+	Name  string `json:"name" binding:"required,tag_name"`
+	Limit int    `query:"limit" binding:"omitempty,min=1,max=100"`
+	Album string `params:"album" binding:"required"`
+}
+
+func newBindApp(t *testing.T) *fiber.App {
+	t.Helper()
+
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler, UnescapePath: true})
+	app.Post("/albums/:album/tags", func(c *fiber.Ctx) error {
+		var req bindRequest
+		if err := Bind(c, &req); err != nil {
+			return err
+		}
+		return c.JSON(req)
+	})
+	return app
+}
+
+func TestBind(t *testing.T) {
+	app := newBindApp(t)
+
+	req := httptest.NewRequest(fiber.MethodPost, "/albums/%E0%B8%97%E0%B8%B0%E0%B9%80%E0%B8%A5/tags?limit=20", strings.NewReader(`{"name":"sunset"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	var got bindRequest
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Name != "sunset" || got.Limit != 20 || got.Album != "ทะเล" {
+		t.Fatalf("bound %+v", got)
+	}
+}
+
+func TestBindOnlyFillsTaggedFields(t *testing.T) {
+	app := newBindApp(t)
+
+	// Untagged names would otherwise match: the query cannot set the body's
+	// name, nor the body the query's limit
+	req := httptest.NewRequest(fiber.MethodPost, "/albums/street/tags?name=evil&Name=evil&album=evil", strings.NewReader(`{"name":"sunset","Limit":5,"Album":"evil"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	var got bindRequest
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Name != "sunset" || got.Limit != 0 || got.Album != "street" {
+		t.Fatalf("bound %+v, want name from the body, no limit and album from the path", got)
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	app := newBindApp(t)
+
+	tests := []struct {
+		name       string
+		target     string
+		body       string
+		wantCode   string
+		wantFields []string
+	}{
+		{name: "malformed body", target: "/albums/a/tags", body: `{"name":`, wantCode: apperror.CodeInvalidRequestBody},
+		{name: "bad query type", target: "/albums/a/tags?limit=many", body: `{"name":"sunset"}`, wantCode: "INVALID_QUERY"},
+		{name: "validation", target: "/albums/a/tags?limit=500", body: `{"name":"bad;name"}`, wantCode: apperror.CodeValidationFailed, wantFields: []string{"name", "limit"}},
+		{name: "missing body", target: "/albums/a/tags", wantCode: apperror.CodeValidationFailed, wantFields: []string{"name"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodPost, tt.target, strings.NewReader(tt.body))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("app.Test: %v", err)
+			}
+			if resp.StatusCode != fiber.StatusBadRequest {
+				t.Errorf("status = %d, want 400", resp.StatusCode)
+			}
+
+			var problem ProblemDetails
+			if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if problem.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", problem.Code, tt.wantCode)
+			}
+			if len(problem.Errors) != len(tt.wantFields) {
+				t.Fatalf("errors = %+v, want fields %v", problem.Errors, tt.wantFields)
+			}
+			for i, field := range tt.wantFields {
+				if problem.Errors[i].Field != field {
+					t.Errorf("errors[%d].field = %q, want %q", i, problem.Errors[i].Field, field)
+				}
+			}
+		})
+	}
+}
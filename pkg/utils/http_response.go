@@ -3,11 +3,32 @@ package utils
 import (
 	"errors"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"pre-test-gallery-service/pkg/apperror"
+	"pre-test-gallery-service/pkg/i18n"
+	"pre-test-gallery-service/pkg/logger"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 )
 
+// MIMEProblemJSON is the RFC 7807 media type used for every error response
+const MIMEProblemJSON = "application/problem+json"
+
+// ProblemDetails is the RFC 7807 error body; code, request_id and errors are
+// extension members
+type ProblemDetails struct {
+	Type      string                `json:"type"`
+	Title     string                `json:"title"`
+	Status    int                   `json:"status"`
+	Detail    string                `json:"detail,omitempty"`
+	Instance  string                `json:"instance,omitempty"`
+	Code      string                `json:"code"`
+	RequestID string                `json:"request_id,omitempty"`
+	Errors    []apperror.FieldError `json:"errors,omitempty"`
+}
+
 func SendSuccess(c *fiber.Ctx, status int, data interface{}, message ...string) error {
 	response := fiber.Map{
 		"success": true,
@@ -21,43 +42,119 @@ func SendSuccess(c *fiber.Ctx, status int, data interface{}, message ...string)
 	return c.Status(status).JSON(response)
 }
 
-func SendError(c *fiber.Ctx, status int, message string) error {
-	return c.Status(status).JSON(fiber.Map{
-		"success": false,
-		"error":   message,
-	})
+// SendError writes err as problem details; the status and code come from
+// the *apperror.Error in its chain, anything else is a 500. The detail and
+// field messages are in the language negotiated for the request, and the
+// cause is only logged
+func SendError(c *fiber.Ctx, err error) error {
+	appErr := apperror.From(err)
+	status := appErr.Status()
+	lang := Language(c)
+	logCause(c, err, appErr, status)
+
+	title := http.StatusText(status)
+	if status == apperror.StatusClientClosedRequest {
+		title = "Client Closed Request"
+	}
+
+	problem := ProblemDetails{
+		Type:     "about:blank",
+		Title:    title,
+		Status:   status,
+		Detail:   appErr.Message,
+		Instance: c.Path(),
+		Code:     appErr.Code,
+		Errors:   appErr.Fields,
+	}
+	problem.RequestID, _ = c.Locals("request_id").(string)
+
+	problem.Detail = Message(lang, appErr)
+	var validationErrors validator.ValidationErrors
+	if errors.As(appErr.Err, &validationErrors) {
+		problem.Errors = FormatValidationError(validationErrors, lang)
+	}
+
+	c.Set(fiber.HeaderContentLanguage, lang)
+	return c.Status(status).JSON(problem, MIMEProblemJSON)
 }
 
-func SendValidationError(c *fiber.Ctx, err error) error {
-	errors := FormatValidationError(err)
-	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-		"success": false,
-		"errors":  errors,
-	})
+// logCause logs the cause of server errors at error level and of rejected
+// credentials at warn level; other client errors explain themselves
+func logCause(c *fiber.Ctx, err error, appErr *apperror.Error, status int) {
+	if appErr.Err == nil {
+		return
+	}
+
+	var level slog.Level
+	switch {
+	case status >= fiber.StatusInternalServerError:
+		level = slog.LevelError
+	case status == fiber.StatusUnauthorized || status == fiber.StatusForbidden:
+		level = slog.LevelWarn
+	default:
+		return
+	}
+
+	logger.FromContext(c.UserContext()).Log(c.UserContext(), level, "Request failed",
+		"code", appErr.Code,
+		"status", status,
+		"error", err.Error(),
+	)
+}
+
+// Message returns the detail of appErr in lang
+func Message(lang string, appErr *apperror.Error) string {
+	if Translator == nil {
+		return appErr.Message
+	}
+	return Translator.Message(lang, appErr.Code, appErr.Message)
+}
+
+// Language returns the language negotiated by the Language middleware,
+// English when it did not run
+func Language(c *fiber.Ctx) string {
+	if lang, ok := c.Locals("language").(string); ok && lang != "" {
+		return lang
+	}
+	return i18n.English
 }
 
-func FormatValidationError(err error) []string {
+// ErrorHandler is the Fiber error handler; errors returned by handlers and
+// raised by the router are all rendered by SendError
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		err = apperror.FromStatus(fiberErr.Code, fiberErr.Message)
+	}
+	return SendError(c, err)
+}
+
+// ValidationError wraps validator errors into a VALIDATION_FAILED error; the
+// field messages are translated when the response is written
+func ValidationError(err error) error {
+	return apperror.Validation(nil).Wrap(err)
+}
+
+// FormatValidationError describes every invalid field in lang, including
+// tags without a catalog entry
+func FormatValidationError(err error, lang string) []apperror.FieldError {
 	var validationErrors validator.ValidationErrors
-	errorMessages := make([]string, 0)
+	fieldErrors := make([]apperror.FieldError, 0)
 
 	if errors.As(err, &validationErrors) {
 		for _, e := range validationErrors {
-			switch e.Tag() {
-			case "required":
-				errorMessages = append(errorMessages, fmt.Sprintf("%s is required", e.Field()))
-			case "email":
-				errorMessages = append(errorMessages, "Invalid email format")
-			case "min":
-				errorMessages = append(errorMessages, fmt.Sprintf("%s must be at least %s characters", e.Field(), e.Param()))
-			case "max":
-				errorMessages = append(errorMessages, fmt.Sprintf("%s must not exceed %s characters", e.Field(), e.Param()))
-			case "eqfield":
-				errorMessages = append(errorMessages, fmt.Sprintf("%s must be equal to %s", e.Field(), e.Param()))
-			case "password_validator":
-				errorMessages = append(errorMessages, "Password must contain at least one uppercase letter, one number, and one special character")
+			message := fmt.Sprintf("%s is invalid", e.Field())
+			if Translator != nil {
+				message = Translator.Field(lang, e)
 			}
+
+			fieldErrors = append(fieldErrors, apperror.FieldError{
+				Field:   e.Field(),
+				Rule:    e.Tag(),
+				Message: message,
+			})
 		}
 	}
 
-	return errorMessages
+	return fieldErrors
 }
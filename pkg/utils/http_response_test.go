@@ -0,0 +1,218 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"pre-test-gallery-service/pkg/apperror"
+	"pre-test-gallery-service/pkg/logger"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var errTagExists = apperror.New(apperror.KindConflict, "TAG_ALREADY_EXISTS", "Tag already exists")
+
+func TestErrorHandlerWritesProblemDetails(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("request_id", "req-1")
+		return c.Next()
+	})
+	app.Get("/conflict", func(c *fiber.Ctx) error {
+		return fmt.Errorf("create tag: %w", errTagExists.Wrap(errors.New("duplicate key")))
+	})
+	app.Get("/validation", func(c *fiber.Ctx) error {
+		return apperror.Validation([]apperror.FieldError{{Field: "name", Rule: "required", Message: "name is required"}})
+	})
+	app.Get("/internal", func(c *fiber.Ctx) error {
+		return errors.New("mongo: connection refused")
+	})
+	app.Get("/timeout", func(c *fiber.Ctx) error {
+		return context.DeadlineExceeded
+	})
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantCode   string
+		wantDetail string
+	}{
+		{path: "/conflict", wantStatus: fiber.StatusConflict, wantCode: "TAG_ALREADY_EXISTS", wantDetail: "Tag already exists"},
+		{path: "/validation", wantStatus: fiber.StatusBadRequest, wantCode: apperror.CodeValidationFailed, wantDetail: "Request validation failed"},
+		// The cause of internal errors is never sent to the client
+		{path: "/internal", wantStatus: fiber.StatusInternalServerError, wantCode: apperror.CodeInternal, wantDetail: "Internal server error"},
+		{path: "/timeout", wantStatus: fiber.StatusGatewayTimeout, wantCode: apperror.CodeRequestTimeout, wantDetail: "Request timed out"},
+		{path: "/missing", wantStatus: fiber.StatusNotFound, wantCode: apperror.CodeNotFound, wantDetail: "Cannot GET /missing"},
+	}
+
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, tt.path, nil))
+		if err != nil {
+			t.Fatalf("app.Test(%s): %v", tt.path, err)
+		}
+
+		if resp.StatusCode != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.path, resp.StatusCode, tt.wantStatus)
+		}
+		if ct := resp.Header.Get(fiber.HeaderContentType); ct != MIMEProblemJSON {
+			t.Errorf("%s: Content-Type = %q, want %q", tt.path, ct, MIMEProblemJSON)
+		}
+
+		var problem ProblemDetails
+		if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil {
+			t.Fatalf("%s: decode: %v", tt.path, err)
+		}
+		if problem.Status != tt.wantStatus || problem.Code != tt.wantCode || problem.Detail != tt.wantDetail {
+			t.Errorf("%s: unexpected problem %+v", tt.path, problem)
+		}
+		if problem.Type != "about:blank" || problem.Instance != tt.path || problem.RequestID != "req-1" {
+			t.Errorf("%s: unexpected problem members %+v", tt.path, problem)
+		}
+		if tt.wantCode == apperror.CodeValidationFailed && (len(problem.Errors) != 1 || problem.Errors[0].Field != "name") {
+			t.Errorf("%s: errors = %+v", tt.path, problem.Errors)
+		}
+	}
+}
+
+func TestSendErrorLogsCause(t *testing.T) {
+	var logs bytes.Buffer
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		logs.Reset()
+		c.SetUserContext(logger.WithContext(c.UserContext(), logger.New(&logs, "debug")))
+		return c.Next()
+	})
+	app.Get("/internal", func(c *fiber.Ctx) error {
+		return errors.New("mongo: connection refused")
+	})
+	app.Get("/login", func(c *fiber.Ctx) error {
+		loginFailed := apperror.New(apperror.KindUnauthorized, "AUTH_LOGIN_FAILED", "Login failed")
+		return SendError(c, loginFailed.Wrap(errors.New("id token: signature mismatch")))
+	})
+	app.Get("/conflict", func(c *fiber.Ctx) error {
+		return errTagExists.Wrap(errors.New("duplicate key"))
+	})
+
+	tests := []struct {
+		path      string
+		wantLevel string
+		wantCause string
+	}{
+		{path: "/internal", wantLevel: slog.LevelError.String(), wantCause: "mongo: connection refused"},
+		{path: "/login", wantLevel: slog.LevelWarn.String(), wantCause: "id token: signature mismatch"},
+		// Client errors that explain themselves are not logged here
+		{path: "/conflict"},
+	}
+
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, tt.path, nil))
+		if err != nil {
+			t.Fatalf("app.Test(%s): %v", tt.path, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+
+		if tt.wantCause == "" {
+			if logs.Len() != 0 {
+				t.Errorf("%s: logged %s", tt.path, logs.String())
+			}
+			continue
+		}
+
+		var entry struct {
+			Level string `json:"level"`
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+			t.Fatalf("%s: decode log %q: %v", tt.path, logs.String(), err)
+		}
+		if entry.Level != tt.wantLevel || !strings.Contains(entry.Error, tt.wantCause) {
+			t.Errorf("%s: log = %+v, want %s with %q", tt.path, entry, tt.wantLevel, tt.wantCause)
+		}
+		if strings.Contains(string(body), tt.wantCause) {
+			t.Errorf("%s: body %s leaks the cause", tt.path, body)
+		}
+	}
+}
+
+func TestAppErrorIsMatchesCode(t *testing.T) {
+	wrapped := fmt.Errorf("service: %w", errTagExists.WithMessage("Tag \"cats\" already exists"))
+	if !errors.Is(wrapped, errTagExists) {
+		t.Fatal("errors.Is does not match the sentinel after WithMessage")
+	}
+	if errors.Is(wrapped, apperror.ErrInternal) {
+		t.Fatal("errors.Is matches an unrelated code")
+	}
+}
+
+func TestSendErrorLocalizesMessages(t *testing.T) {
+	if err := SetupValidator(); err != nil {
+		t.Fatalf("SetupValidator: %v", err)
+	}
+
+	type request struct {
+		Name   string `json:"name" binding:"required"`
+		Status string `json:"status" binding:"oneof=draft published"`
+	}
+
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		lang := "en"
+		if c.Get(fiber.HeaderAcceptLanguage) == "th" {
+			lang = "th"
+		}
+		c.Locals("language", lang)
+		return c.Next()
+	})
+	app.Get("/validation", func(c *fiber.Ctx) error {
+		return ValidationError(ValidateStruct(&request{Status: "archived"}))
+	})
+	app.Get("/conflict", func(c *fiber.Ctx) error {
+		return errTagExists
+	})
+
+	tests := []struct {
+		path, lang string
+		wantDetail string
+		wantFields []string
+	}{
+		{path: "/validation", lang: "en", wantDetail: "Request validation failed", wantFields: []string{"name is a required field", "status must be one of [draft published]"}},
+		{path: "/validation", lang: "th", wantDetail: "ข้อมูลในคำขอไม่ผ่านการตรวจสอบ", wantFields: []string{"name จำเป็นต้องระบุ", "status ต้องเป็นค่าใดค่าหนึ่งต่อไปนี้ [draft published]"}},
+		{path: "/conflict", lang: "th", wantDetail: "มีแท็กนี้อยู่แล้ว"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(fiber.MethodGet, tt.path, nil)
+		req.Header.Set(fiber.HeaderAcceptLanguage, tt.lang)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test(%s): %v", tt.path, err)
+		}
+		if got := resp.Header.Get(fiber.HeaderContentLanguage); got != tt.lang {
+			t.Errorf("%s %s: Content-Language = %q", tt.path, tt.lang, got)
+		}
+
+		var problem ProblemDetails
+		if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if problem.Detail != tt.wantDetail {
+			t.Errorf("%s %s: detail = %q, want %q", tt.path, tt.lang, problem.Detail, tt.wantDetail)
+		}
+		if len(problem.Errors) != len(tt.wantFields) {
+			t.Fatalf("%s %s: errors = %+v", tt.path, tt.lang, problem.Errors)
+		}
+		for i, want := range tt.wantFields {
+			if problem.Errors[i].Message != want {
+				t.Errorf("%s %s: errors[%d] = %q, want %q", tt.path, tt.lang, i, problem.Errors[i].Message, want)
+			}
+		}
+	}
+}
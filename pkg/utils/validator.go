@@ -1,40 +1,153 @@
 package utils
 
 import (
+	"pre-test-gallery-service/pkg/i18n"
 	"reflect"
 	"regexp"
+	"strings"
+	"sync"
 
 	"github.com/go-playground/validator/v10"
 )
 
+// Validate reads the binding struct tag; it is configured once by
+// SetupValidator and is safe for concurrent use afterwards
 var Validate *validator.Validate
 
-func SetupValidator() {
-	Validate = validator.New()
-	Validate.RegisterTagNameFunc(func(fld reflect.StructField) string {
-		name := fld.Tag.Get("json")
+// Translator localizes validation and error messages; it is set up together
+// with Validate
+var Translator *i18n.Translator
+
+// ReservedTagNames cannot be used as tag names because they collide with
+// routes or read as special values (compared case-insensitively)
+var ReservedTagNames = []string{"all", "new", "none", "null", "undefined", "untagged"}
+
+var (
+	setupOnce sync.Once
+	setupErr  error
+
+	upperPattern   = regexp.MustCompile(`[A-Z]`)
+	lowerPattern   = regexp.MustCompile(`[a-z]`)
+	numberPattern  = regexp.MustCompile(`[0-9]`)
+	specialPattern = regexp.MustCompile(`[!@#$%^&*()+\-_=\[\]{}|;:,.<>?]`)
+	// Letters and marks of any script (Thai vowels and tone marks are marks),
+	// digits, spaces, hyphens and underscores, starting with a letter or digit
+	tagNamePattern = regexp.MustCompile(`^[\p{L}\p{N}][\p{L}\p{M}\p{N} _-]*$`)
+)
+
+// customValidation is a rule registered on Validate with its messages
+type customValidation struct {
+	tag      string
+	fn       validator.Func
+	messages map[string]string
+}
+
+var customValidations = []customValidation{
+	{
+		tag: "password_validator",
+		fn:  PasswordValidator,
+		messages: map[string]string{
+			i18n.English: "{0} must contain at least one uppercase letter, one lowercase letter, one number and one special character",
+			i18n.Thai:    "{0} ต้องมีตัวพิมพ์ใหญ่ ตัวพิมพ์เล็ก ตัวเลข และอักขระพิเศษอย่างน้อยอย่างละหนึ่งตัว",
+		},
+	},
+	{
+		tag: "tag_name",
+		fn:  TagNameValidator,
+		messages: map[string]string{
+			i18n.English: "{0} may only contain letters, numbers, spaces, hyphens and underscores and must start with a letter or number",
+			i18n.Thai:    "{0} ต้องประกอบด้วยตัวอักษร ตัวเลข ช่องว่าง ขีดกลาง หรือขีดล่างเท่านั้น และต้องขึ้นต้นด้วยตัวอักษรหรือตัวเลข",
+		},
+	},
+	{
+		tag: "not_reserved",
+		fn:  NotReservedValidator,
+		messages: map[string]string{
+			i18n.English: "{0} is a reserved word",
+			i18n.Thai:    "{0} เป็นคำสงวน ไม่สามารถใช้ได้",
+		},
+	},
+}
+
+// SetupValidator configures Validate and Translator; later calls return the
+// result of the first one
+func SetupValidator() error {
+	setupOnce.Do(func() {
+		setupErr = setupValidator()
+	})
+	return setupErr
+}
+
+func setupValidator() error {
+	v := validator.New()
+	v.SetTagName("binding")
+	v.RegisterTagNameFunc(fieldName)
+
+	translator, err := i18n.New(v)
+	if err != nil {
+		return err
+	}
+
+	for _, custom := range customValidations {
+		if err := v.RegisterValidation(custom.tag, custom.fn); err != nil {
+			return err
+		}
+		if err := translator.RegisterValidation(v, custom.tag, custom.messages); err != nil {
+			return err
+		}
+	}
+
+	Validate = v
+	Translator = translator
+	return nil
+}
+
+// fieldName reports fields by the name the client sent: the json, query or
+// params tag, in that order
+func fieldName(fld reflect.StructField) string {
+	for _, key := range []string{"json", "query", "params"} {
+		name, _, _ := strings.Cut(fld.Tag.Get(key), ",")
 		if name == "-" {
 			return ""
 		}
-		return name
-	})
-
-	if err := Validate.RegisterValidation("password_validator", PasswordValidator); err != nil {
-		return
+		if name != "" {
+			return name
+		}
 	}
+	return ""
 }
 
 func PasswordValidator(fl validator.FieldLevel) bool {
 	password := fl.Field().String()
 
-	hasUpper := regexp.MustCompile(`[A-Z]`).MatchString(password)
-	hasLower := regexp.MustCompile(`[a-z]`).MatchString(password)
-	hasNumber := regexp.MustCompile(`[0-9]`).MatchString(password)
-	hasSpecial := regexp.MustCompile(`[!@#$%^&*()+-_=\[\]{}|;:,.<>?]`).MatchString(password)
+	hasUpper := upperPattern.MatchString(password)
+	hasLower := lowerPattern.MatchString(password)
+	hasNumber := numberPattern.MatchString(password)
+	hasSpecial := specialPattern.MatchString(password)
 
 	return hasUpper && hasNumber && hasSpecial && hasLower
 }
+
+// TagNameValidator accepts names in any script without leading or trailing
+// whitespace
+func TagNameValidator(fl validator.FieldLevel) bool {
+	name := fl.Field().String()
+	return strings.TrimSpace(name) == name && tagNamePattern.MatchString(name)
+}
+
+func NotReservedValidator(fl validator.FieldLevel) bool {
+	value := strings.TrimSpace(fl.Field().String())
+	for _, reserved := range ReservedTagNames {
+		if strings.EqualFold(value, reserved) {
+			return false
+		}
+	}
+	return true
+}
+
 func ValidateStruct(payload interface{}) error {
-	Validate.SetTagName("binding")
+	if err := SetupValidator(); err != nil {
+		return err
+	}
 	return Validate.Struct(payload)
 }
@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func mustSetupValidator(t *testing.T) {
+	t.Helper()
+
+	if err := SetupValidator(); err != nil {
+		t.Fatalf("SetupValidator: %v", err)
+	}
+}
+
+func TestPasswordValidator(t *testing.T) {
+	mustSetupValidator(t)
+
+	tests := []struct {
+		password string
+		valid    bool
+	}{
+		{password: "Passw0rd!", valid: true},
+		{password: "Passw0rd-", valid: true},
+		{password: "Passw0rd_", valid: true},
+		{password: "Passw0rd+", valid: true},
+		// Uppercase letters and digits used to satisfy the special character
+		// class through the unescaped +-_ range
+		{password: "Password1", valid: false},
+		{password: "PASSWORD1a", valid: false},
+		{password: "passw0rd!", valid: false},
+		{password: "PASSW0RD!", valid: false},
+		{password: "Password!", valid: false},
+	}
+
+	for _, tt := range tests {
+		err := Validate.Var(tt.password, "password_validator")
+		if (err == nil) != tt.valid {
+			t.Errorf("password %q: err = %v, want valid=%v", tt.password, err, tt.valid)
+		}
+	}
+}
+
+func TestTagNameRules(t *testing.T) {
+	mustSetupValidator(t)
+
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{name: "landscape", valid: true},
+		{name: "Street Photo", valid: true},
+		{name: "black_and-white", valid: true},
+		{name: "2024", valid: true},
+		{name: "ทะเล", valid: true},
+		{name: "ภูเขา ฤดูหนาว", valid: true},
+		{name: strings.Repeat("ก", 50), valid: true},
+		{name: strings.Repeat("ก", 51), valid: false},
+		{name: "", valid: false},
+		{name: " leading", valid: false},
+		{name: "trailing ", valid: false},
+		{name: "-dash", valid: false},
+		{name: "semi;colon", valid: false},
+		{name: "<script>", valid: false},
+		{name: "all", valid: false},
+		{name: "NULL", valid: false},
+	}
+
+	for _, tt := range tests {
+		err := Validate.Var(tt.name, "required,max=50,tag_name,not_reserved")
+		if (err == nil) != tt.valid {
+			t.Errorf("tag name %q: err = %v, want valid=%v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestCustomRulesAreTranslated(t *testing.T) {
+	mustSetupValidator(t)
+
+	type request struct {
+		Name string `json:"name" binding:"not_reserved"`
+	}
+
+	err := ValidateStruct(&request{Name: "undefined"})
+	fields := FormatValidationError(err, "th")
+	if len(fields) != 1 || fields[0].Rule != "not_reserved" || fields[0].Message != "name เป็นคำสงวน ไม่สามารถใช้ได้" {
+		t.Fatalf("fields = %+v", fields)
+	}
+}
+
+func TestValidateStructConcurrently(t *testing.T) {
+	type request struct {
+		Name string `json:"name" binding:"required"`
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(valid bool) {
+			defer wg.Done()
+
+			req := request{}
+			if valid {
+				req.Name = "tag"
+			}
+
+			err := ValidateStruct(&req)
+			var validationErrors validator.ValidationErrors
+			if valid && err != nil {
+				t.Errorf("valid request: %v", err)
+			}
+			if !valid && !errors.As(err, &validationErrors) {
+				t.Errorf("invalid request: err = %v", err)
+			}
+		}(i%2 == 0)
+	}
+	wg.Wait()
+}